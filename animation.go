@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// AnimationFrame is a single frame of a clip: where it is on the spritesheet, and how long it's shown for
+type AnimationFrame struct {
+	Duration time.Duration
+	Source   rl.Rectangle
+}
+
+// Clip is a named animation, like a person's walk cycle. It loops once it reaches the end
+type Clip struct {
+	Frames []AnimationFrame
+	Name   string
+}
+
+// Animations are the clips cut from a spritesheet, by name
+type Animations struct {
+	Clips map[string]*Clip
+	// Image is the spritesheet the clips are drawn from, as named in the export
+	Image string
+}
+
+// Group returns the clips named prefix/something, by the something. It lets a sheet with several characters laid out
+// the same way, like person0/walk and person1/walk, hand each character its own set
+func (a *Animations) Group(prefix string) map[string]*Clip {
+	group := make(map[string]*Clip)
+	for name, clip := range a.Clips {
+		if strings.HasPrefix(name, prefix+"/") {
+			group[strings.TrimPrefix(name, prefix+"/")] = clip
+		}
+	}
+	return group
+}
+
+// asepriteFrame is a frame as Aseprite exports it
+type asepriteFrame struct {
+	Duration int `json:"duration"`
+	Frame    struct {
+		X, Y, W, H float32
+	} `json:"frame"`
+}
+
+// asepriteExport is the JSON Aseprite exports next to a spritesheet. Only what we use is read
+type asepriteExport struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		FrameTags []struct {
+			Direction string `json:"direction"`
+			From      int    `json:"from"`
+			Name      string `json:"name"`
+			To        int    `json:"to"`
+		} `json:"frameTags"`
+		Image string `json:"image"`
+	} `json:"meta"`
+}
+
+// ParseAseprite reads the clips out of an Aseprite JSON export. Each frame tag becomes a clip, and the frames can be
+// exported as either an array or a hash
+func ParseAseprite(data []byte) (*Animations, error) {
+	export := asepriteExport{}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	frames, err := asepriteFrames(export.Frames)
+	if err != nil {
+		return nil, err
+	}
+
+	animations := &Animations{Clips: make(map[string]*Clip), Image: export.Meta.Image}
+	for _, tag := range export.Meta.FrameTags {
+		if tag.From < 0 || tag.To >= len(frames) || tag.From > tag.To {
+			return nil, fmt.Errorf("tag %v covers frames %v to %v, but there are only %v", tag.Name, tag.From, tag.To, len(frames))
+		}
+		order := []int{}
+		for i := tag.From; i <= tag.To; i++ {
+			order = append(order, i)
+		}
+		switch tag.Direction {
+		case "reverse":
+			for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+				order[i], order[j] = order[j], order[i]
+			}
+		case "pingpong":
+			// Back down again without repeating either end, so the loop doesn't stutter
+			for i := len(order) - 2; i > 0; i-- {
+				order = append(order, order[i])
+			}
+		}
+
+		clip := &Clip{Name: tag.Name}
+		for _, i := range order {
+			frame := frames[i]
+			clip.Frames = append(clip.Frames, AnimationFrame{
+				Duration: time.Duration(frame.Duration) * time.Millisecond,
+				Source:   rl.NewRectangle(frame.Frame.X, frame.Frame.Y, frame.Frame.W, frame.Frame.H),
+			})
+		}
+		animations.Clips[tag.Name] = clip
+	}
+	return animations, nil
+}
+
+// asepriteFrames reads the frames in the order they were exported. A hash has to be walked token by token, since
+// decoding it into a map would lose the order
+func asepriteFrames(raw json.RawMessage) ([]asepriteFrame, error) {
+	frames := []asepriteFrame{}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &frames)
+		return frames, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		// The key is the frame's filename, which we've no use for
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		frame := asepriteFrame{}
+		if err := decoder.Decode(&frame); err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// animationCache holds each export once it's been loaded, so every sprite shares its clips
+var animationCache = make(map[string]*Animations)
+
+// LoadAnimations reads the clips from an Aseprite export in the assets, loading it the first time it's asked for
+func LoadAnimations(filepath string) (*Animations, error) {
+	if animations, ok := animationCache[filepath]; ok {
+		return animations, nil
+	}
+	data, err := ReadAsset(filepath)
+	if err != nil {
+		return nil, err
+	}
+	animations, err := ParseAseprite(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filepath, err)
+	}
+	animationCache[filepath] = animations
+	return animations, nil
+}
+
+// ReloadAnimations rereads an export that's already loaded, and swaps the new frames into its clips so every sprite
+// playing them picks up the change
+func ReloadAnimations(filepath string) error {
+	animations, ok := animationCache[filepath]
+	if !ok {
+		return nil
+	}
+	data, err := ReadAsset(filepath)
+	if err != nil {
+		return err
+	}
+	reloaded, err := ParseAseprite(data)
+	if err != nil {
+		return err
+	}
+	for name, clip := range reloaded.Clips {
+		if existing, ok := animations.Clips[name]; ok {
+			existing.Frames = clip.Frames
+		} else {
+			animations.Clips[name] = clip
+		}
+	}
+	return nil
+}
+
+// MegaClips returns a group of clips from the mega spritesheet, like "taxi" or "person2"
+func MegaClips(group string) map[string]*Clip {
+	animations, err := LoadAnimations("assets/sprites/mega.json")
+	if err != nil {
+		fmt.Printf("Couldn't load animations: %v\n", err)
+		return nil
+	}
+	return animations.Group(group)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAseprite(t *testing.T) {
+	// Frames exported as a hash, in the order they're listed rather than sorted by name
+	export := `{
+		"frames": {
+			"b.aseprite 0": {"frame": {"x": 0, "y": 0, "w": 16, "h": 16}, "duration": 100},
+			"a.aseprite 1": {"frame": {"x": 16, "y": 0, "w": 16, "h": 16}, "duration": 200},
+			"c.aseprite 2": {"frame": {"x": 32, "y": 0, "w": 16, "h": 16}, "duration": 300}
+		},
+		"meta": {"image": "sheet.png", "frameTags": [
+			{"name": "forward", "from": 0, "to": 2, "direction": "forward"},
+			{"name": "reverse", "from": 0, "to": 2, "direction": "reverse"},
+			{"name": "pingpong", "from": 0, "to": 2, "direction": "pingpong"}
+		]}
+	}`
+	animations, err := ParseAseprite([]byte(export))
+	assert.NoError(t, err)
+	assert.Equal(t, "sheet.png", animations.Image)
+
+	xs := func(clip *Clip) []float32 {
+		list := []float32{}
+		for _, frame := range clip.Frames {
+			list = append(list, frame.Source.X)
+		}
+		return list
+	}
+	assert.Equal(t, []float32{0, 16, 32}, xs(animations.Clips["forward"]))
+	assert.Equal(t, []float32{32, 16, 0}, xs(animations.Clips["reverse"]))
+	assert.Equal(t, []float32{0, 16, 32, 16}, xs(animations.Clips["pingpong"]))
+	assert.Equal(t, 200*time.Millisecond, animations.Clips["forward"].Frames[1].Duration)
+
+	_, err = ParseAseprite([]byte(`{"frames": [], "meta": {"frameTags": [{"name": "missing", "from": 0, "to": 1}]}}`))
+	assert.Error(t, err)
+}
+
+func TestMegaClips(t *testing.T) {
+	for _, group := range []string{"person0", "person1", "person2", "person3"} {
+		clips := MegaClips(group)
+		for _, name := range []string{"idle", "walk", "fall", "carried", "sleep"} {
+			assert.Contains(t, clips, name, group)
+		}
+	}
+	assert.Len(t, MegaClips("person1")["walk"].Frames, 4)
+	assert.Equal(t, rl.NewRectangle(32, 896, 32, 32), MegaClips("person1")["walk"].Frames[0].Source)
+	assert.Contains(t, MegaClips("taxi"), "drive")
+}
+
+func TestSpritePlaysClips(t *testing.T) {
+	tick := time.Second / TickRate
+	sprite := &Sprite{XPos: 0, YPos: 864, Width: 32, Height: 32}
+	sprite.Clips = map[string]*Clip{
+		"idle": {Frames: []AnimationFrame{{Duration: time.Second, Source: rl.NewRectangle(0, 864, 32, 32)}}},
+		"walk": {Frames: []AnimationFrame{
+			{Duration: 2 * tick, Source: rl.NewRectangle(32, 864, 32, 32)},
+			{Duration: 3 * tick, Source: rl.NewRectangle(64, 864, 32, 32)},
+		}},
+	}
+	// Without a clip the sprite is drawn from where it was set up
+	assert.Equal(t, rl.NewRectangle(0, 864, 32, 32), sprite.Source())
+
+	sprite.Play("walk")
+	sources := []float32{}
+	for i := 0; i < 6; i++ {
+		sources = append(sources, sprite.Source().X)
+		sprite.Update()
+	}
+	assert.Equal(t, []float32{32, 32, 64, 64, 64, 32}, sources)
+
+	// Playing the same clip carries on, and an unknown one is ignored
+	sprite.Play("walk")
+	sprite.Play("dance")
+	assert.Equal(t, float32(32), sprite.Source().X)
+	assert.Equal(t, tick, sprite.Elapsed)
+
+	sprite.Play("idle")
+	assert.Equal(t, 0, sprite.Frame)
+	assert.Equal(t, float32(0), sprite.Source().X)
+}
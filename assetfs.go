@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// embeddedAssets bundles the assets directory into the binary, so the game runs from anywhere
+//
+//go:embed assets
+var embeddedAssets embed.FS
+
+// AssetFS is where every asset is read from, by its slash separated path like "assets/sprites/mega.png".
+// It's the embedded bundle, with any mods layered over it, and the override directory over those
+var AssetFS fs.FS = embeddedAssets
+
+var (
+	// assetOverride is the override directory, if there is one
+	assetOverride fs.FS
+	// modAssets are the enabled mods, the one that wins any conflicts first
+	modAssets []fs.FS
+)
+
+// LayeredFS reads each file from the first layer that has it
+type LayeredFS []fs.FS
+
+// Open opens the named file from the first layer that has it
+func (l LayeredFS) Open(name string) (fs.File, error) {
+	for _, layer := range l {
+		file, err := layer.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// UseAssetOverride layers a directory over the embedded assets. It's laid out like the repo, so a file at
+// <dir>/assets/sprites/mega.png replaces the bundled mega.png, and anything it doesn't have comes from the bundle
+func UseAssetOverride(dir string) {
+	assetOverride = os.DirFS(dir)
+	layerAssets()
+}
+
+// UseModAssets layers the mods' assets over the embedded ones, the first layer winning any conflicts
+func UseModAssets(layers []fs.FS) {
+	modAssets = layers
+	layerAssets()
+}
+
+// layerAssets stacks up the override directory, then the mods, then the embedded assets
+func layerAssets() {
+	layers := LayeredFS{}
+	if assetOverride != nil {
+		layers = append(layers, assetOverride)
+	}
+	layers = append(layers, modAssets...)
+	AssetFS = append(layers, embeddedAssets)
+}
+
+// ReadAsset returns the contents of an asset
+func ReadAsset(name string) ([]byte, error) {
+	return fs.ReadFile(AssetFS, name)
+}
+
+// AssetPath returns a path on disk for the asset, for raylib's loaders which can only read files. The asset is copied
+// out to the user's cache directory, named by its contents, so it's only ever written once and a changed asset never
+// collides with an old copy
+func AssetPath(name string) (string, error) {
+	data, err := ReadAsset(name)
+	if err != nil {
+		return "", err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	sum := sha256.Sum256(data)
+	dir := filepath.Join(cache, "pixelopolis", hex.EncodeToString(sum[:8]))
+	extracted := filepath.Join(dir, path.Base(name))
+	if _, err := os.Stat(extracted); err == nil {
+		return extracted, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// Write somewhere else first and move it into place, so a half written file is never picked up
+	temp, err := ioutil.TempFile(dir, "extract")
+	if err != nil {
+		return "", err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return "", err
+	}
+	return extracted, os.Rename(temp.Name(), extracted)
+}
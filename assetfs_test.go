@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayeredFS(t *testing.T) {
+	bundled := fstest.MapFS{
+		"assets/a.txt": {Data: []byte("bundled a")},
+		"assets/b.txt": {Data: []byte("bundled b")},
+	}
+	override := fstest.MapFS{"assets/a.txt": {Data: []byte("modded a")}}
+	layered := LayeredFS{override, bundled}
+
+	data, err := fs.ReadFile(layered, "assets/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "modded a", string(data))
+	data, err = fs.ReadFile(layered, "assets/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "bundled b", string(data))
+	_, err = layered.Open("assets/c.txt")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestAssetPath(t *testing.T) {
+	cache, err := ioutil.TempDir("", "pixelopolis")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cache)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", cache)
+
+	bundled, err := ReadAsset("assets/buildings/slum/2.json")
+	assert.NoError(t, err)
+
+	path, err := AssetPath("assets/buildings/slum/2.json")
+	assert.NoError(t, err)
+	extracted, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, bundled, extracted)
+
+	again, err := AssetPath("assets/buildings/slum/2.json")
+	assert.NoError(t, err)
+	assert.Equal(t, path, again)
+}
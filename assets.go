@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// asset is a loaded texture, sound or music stream, and how many handles to it are out
+type asset struct {
+	// Bytes is roughly how much GPU or audio memory the asset takes up
+	Bytes int64
+	Music rl.Music
+	// Recolor is how a recolored texture was generated from its Source
+	Recolor Recolor
+	Refs    int
+	Sound   rl.Sound
+	Source  string
+	Texture rl.Texture2D
+}
+
+// AssetReport sums up what the asset manager has loaded
+type AssetReport struct {
+	Music, Sounds, Textures int
+	// Refs is how many handles are out across every asset
+	Refs                     int
+	SoundBytes, TextureBytes int64
+}
+
+// String formats the report for the debug overlay
+func (r AssetReport) String() string {
+	return fmt.Sprintf("Assets: %v textures (%.1f MB), %v sounds (%.1f MB), %v music, %v handles",
+		r.Textures, float64(r.TextureBytes)/(1<<20), r.Sounds, float64(r.SoundBytes)/(1<<20), r.Music, r.Refs)
+}
+
+// AssetManager loads each texture, sound and music stream once by its path, and hands out shared handles to it.
+// Every handle taken should be given back with the matching Release, and the asset is unloaded when the last one is
+type AssetManager struct {
+	music, sounds, textures map[string]*asset
+
+	// The loaders default to raylib's, and can be swapped out to run without a window or audio device
+	loadMusic     func(string) rl.Music
+	loadPixels    func(width, height int, pixels []rl.Color) rl.Texture2D
+	loadSound     func(string) rl.Sound
+	loadTexture   func(string) rl.Texture2D
+	unloadMusic   func(rl.Music)
+	unloadSound   func(rl.Sound)
+	unloadTexture func(rl.Texture2D)
+	updateTexture func(rl.Texture2D, []rl.Color)
+}
+
+// Assets is the game's asset manager. Load everything from disk through it
+var Assets = NewAssetManager()
+
+// resolveAsset finds the asset on disk for raylib. Anything missing is passed through as is, so raylib reports it
+func resolveAsset(name string) string {
+	path, err := AssetPath(name)
+	if err != nil {
+		fmt.Printf("Couldn't find asset %v: %v\n", name, err)
+		return name
+	}
+	return path
+}
+
+// NewAssetManager returns an empty asset manager that loads from AssetFS through raylib
+func NewAssetManager() *AssetManager {
+	return &AssetManager{
+		music:    make(map[string]*asset),
+		sounds:   make(map[string]*asset),
+		textures: make(map[string]*asset),
+		loadMusic: func(name string) rl.Music {
+			return rl.LoadMusicStream(resolveAsset(name))
+		},
+		loadPixels: func(width, height int, pixels []rl.Color) rl.Texture2D {
+			data := make([]byte, 0, len(pixels)*4)
+			for _, pixel := range pixels {
+				data = append(data, pixel.R, pixel.G, pixel.B, pixel.A)
+			}
+			return rl.LoadTextureFromImage(rl.NewImage(data, int32(width), int32(height), 1, rl.UncompressedR8g8b8a8))
+		},
+		loadSound: func(name string) rl.Sound {
+			return rl.LoadSound(resolveAsset(name))
+		},
+		loadTexture: func(name string) rl.Texture2D {
+			return rl.LoadTexture(resolveAsset(name))
+		},
+		unloadMusic:   rl.UnloadMusicStream,
+		unloadSound:   rl.UnloadSound,
+		unloadTexture: rl.UnloadTexture,
+		updateTexture: rl.UpdateTexture,
+	}
+}
+
+// Texture returns the texture at the path, loading it if it's not already loaded
+func (a *AssetManager) Texture(filepath string) rl.Texture2D {
+	loaded, ok := a.textures[filepath]
+	if !ok {
+		texture := a.loadTexture(filepath)
+		// Textures are uploaded as 32 bit RGBA
+		loaded = &asset{Bytes: int64(texture.Width) * int64(texture.Height) * 4, Texture: texture}
+		a.textures[filepath] = loaded
+	}
+	loaded.Refs++
+	return loaded.Texture
+}
+
+// RecoloredTexture returns the texture at the path recolored, generating it from the texture's pixels if it's not
+// already loaded. It's looked up by the returned key, which is also what to release it by
+func (a *AssetManager) RecoloredTexture(filepath, variant string, recolor Recolor) (rl.Texture2D, string) {
+	key := filepath + "#" + variant
+	loaded, ok := a.textures[key]
+	if !ok {
+		loaded = &asset{Recolor: recolor, Source: filepath}
+		width, height, pixels, err := a.recolor(loaded)
+		if err != nil {
+			fmt.Printf("Couldn't recolor %v: %v\n", filepath, err)
+		}
+		loaded.Texture = a.loadPixels(width, height, pixels)
+		loaded.Bytes = int64(width) * int64(height) * 4
+		a.textures[key] = loaded
+	}
+	loaded.Refs++
+	return loaded.Texture, key
+}
+
+// recolor reads a recolored texture's source from AssetFS, and recolors its pixels
+func (a *AssetManager) recolor(loaded *asset) (int, int, []rl.Color, error) {
+	data, err := ReadAsset(loaded.Source)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	width, height, pixels, err := decodeColors(data)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	loaded.Recolor.Apply(pixels)
+	return width, height, pixels, nil
+}
+
+// ReleaseTexture gives back a handle to the texture, unloading it once nothing is using it
+func (a *AssetManager) ReleaseTexture(filepath string) {
+	if loaded := a.release(a.textures, filepath); loaded != nil {
+		a.unloadTexture(loaded.Texture)
+	}
+}
+
+// ReloadTexture rereads a loaded texture from AssetFS and returns it. If it's the same size it's redrawn in place, so
+// every handle out to it shows the change. If it's been resized it has to be loaded again, and only the returned
+// handle is good; older ones keep drawing the old texture until they're released. Recolored textures are generated
+// again from their source
+func (a *AssetManager) ReloadTexture(filepath string) (rl.Texture2D, bool) {
+	loaded, ok := a.textures[filepath]
+	if !ok {
+		return rl.Texture2D{}, false
+	}
+	var width, height int
+	var pixels []rl.Color
+	var err error
+	if loaded.Source != "" {
+		width, height, pixels, err = a.recolor(loaded)
+	} else {
+		var data []byte
+		if data, err = ReadAsset(filepath); err == nil {
+			width, height, pixels, err = decodeColors(data)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Couldn't reload %v: %v\n", filepath, err)
+		return loaded.Texture, false
+	}
+
+	if int32(width) == loaded.Texture.Width && int32(height) == loaded.Texture.Height {
+		a.updateTexture(loaded.Texture, pixels)
+		return loaded.Texture, true
+	}
+	fmt.Printf("%v was resized, so sprites already using it won't change until they're recreated\n", filepath)
+	old := loaded.Texture
+	if loaded.Source != "" {
+		loaded.Texture = a.loadPixels(width, height, pixels)
+	} else {
+		loaded.Texture = a.loadTexture(filepath)
+	}
+	loaded.Bytes = int64(loaded.Texture.Width) * int64(loaded.Texture.Height) * 4
+	a.unloadTexture(old)
+	return loaded.Texture, true
+}
+
+// Sound returns the sound at the path, loading it if it's not already loaded
+func (a *AssetManager) Sound(filepath string) rl.Sound {
+	loaded, ok := a.sounds[filepath]
+	if !ok {
+		sound := a.loadSound(filepath)
+		loaded = &asset{Bytes: int64(sound.SampleCount) * int64(sound.Stream.SampleSize/8), Sound: sound}
+		a.sounds[filepath] = loaded
+	}
+	loaded.Refs++
+	return loaded.Sound
+}
+
+// ReleaseSound gives back a handle to the sound, unloading it once nothing is using it
+func (a *AssetManager) ReleaseSound(filepath string) {
+	if loaded := a.release(a.sounds, filepath); loaded != nil {
+		a.unloadSound(loaded.Sound)
+	}
+}
+
+// Music returns the music stream at the path, opening it if it's not already open. Music streams from disk, so it
+// isn't counted towards memory
+func (a *AssetManager) Music(filepath string) rl.Music {
+	loaded, ok := a.music[filepath]
+	if !ok {
+		loaded = &asset{Music: a.loadMusic(filepath)}
+		a.music[filepath] = loaded
+	}
+	loaded.Refs++
+	return loaded.Music
+}
+
+// ReleaseMusic gives back a handle to the music stream, closing it once nothing is using it
+func (a *AssetManager) ReleaseMusic(filepath string) {
+	if loaded := a.release(a.music, filepath); loaded != nil {
+		a.unloadMusic(loaded.Music)
+	}
+}
+
+// release drops a reference to the asset, and returns it if that was the last one so the caller can unload it
+func (a *AssetManager) release(assets map[string]*asset, filepath string) *asset {
+	loaded, ok := assets[filepath]
+	if !ok {
+		return nil
+	}
+	loaded.Refs--
+	if loaded.Refs > 0 {
+		return nil
+	}
+	delete(assets, filepath)
+	return loaded
+}
+
+// Report sums up everything that's loaded
+func (a *AssetManager) Report() AssetReport {
+	report := AssetReport{Music: len(a.music), Sounds: len(a.sounds), Textures: len(a.textures)}
+	for _, loaded := range a.music {
+		report.Refs += loaded.Refs
+	}
+	for _, loaded := range a.sounds {
+		report.Refs += loaded.Refs
+		report.SoundBytes += loaded.Bytes
+	}
+	for _, loaded := range a.textures {
+		report.Refs += loaded.Refs
+		report.TextureBytes += loaded.Bytes
+	}
+	return report
+}
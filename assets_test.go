@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubAssets returns an asset manager that fakes loading, and counts how many times each texture was loaded and unloaded
+func stubAssets() (assets *AssetManager, loads, unloads map[string]int) {
+	loads, unloads = make(map[string]int), make(map[string]int)
+	ids := make(map[uint32]string)
+	assets = NewAssetManager()
+	assets.loadTexture = func(filepath string) rl.Texture2D {
+		loads[filepath]++
+		id := uint32(len(ids) + 1)
+		ids[id] = filepath
+		return rl.Texture2D{ID: id, Width: 16, Height: 16}
+	}
+	assets.unloadTexture = func(texture rl.Texture2D) {
+		unloads[ids[texture.ID]]++
+	}
+	return assets, loads, unloads
+}
+
+func TestAssetsLoadOnce(t *testing.T) {
+	assets, loads, unloads := stubAssets()
+	first := assets.Texture("mega.png")
+	second := assets.Texture("mega.png")
+	assets.Texture("ui.png")
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, loads["mega.png"])
+	report := assets.Report()
+	assert.Equal(t, 2, report.Textures)
+	assert.Equal(t, 3, report.Refs)
+	assert.Equal(t, int64(2*16*16*4), report.TextureBytes)
+
+	// The texture sticks around until the last handle is given back
+	assets.ReleaseTexture("mega.png")
+	assert.Equal(t, 0, unloads["mega.png"])
+	assets.ReleaseTexture("mega.png")
+	assert.Equal(t, 1, unloads["mega.png"])
+	assert.Equal(t, 1, assets.Report().Textures)
+
+	// Releasing too many times is harmless
+	assets.ReleaseTexture("mega.png")
+	assert.Equal(t, 1, unloads["mega.png"])
+
+	assets.Texture("mega.png")
+	assert.Equal(t, 2, loads["mega.png"])
+}
+
+func TestReloadTextureInPlace(t *testing.T) {
+	data, err := ReadAsset("assets/sprites/ui.png")
+	assert.NoError(t, err)
+	width, height, _, err := decodeColors(data)
+	assert.NoError(t, err)
+
+	assets, loads, _ := stubAssets()
+	assets.loadTexture = func(filepath string) rl.Texture2D {
+		loads[filepath]++
+		return rl.Texture2D{ID: 1, Width: int32(width), Height: int32(height)}
+	}
+	updated := 0
+	assets.updateTexture = func(texture rl.Texture2D, pixels []rl.Color) {
+		assert.Len(t, pixels, width*height)
+		updated++
+	}
+
+	original := assets.Texture("assets/sprites/ui.png")
+	reloaded, ok := assets.ReloadTexture("assets/sprites/ui.png")
+	assert.True(t, ok)
+	assert.Equal(t, original, reloaded)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, 1, loads["assets/sprites/ui.png"])
+
+	_, ok = assets.ReloadTexture("assets/sprites/unloaded.png")
+	assert.False(t, ok)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// These draw through raylib. The tests and benchmarks swap them out to count draw calls without a window
+var (
+	beginDrawing        = rl.BeginDrawing
+	beginTextureMode    = rl.BeginTextureMode
+	clearBackground     = rl.ClearBackground
+	drawTextureRec      = rl.DrawTextureRec
+	endTextureMode      = rl.EndTextureMode
+	loadRenderTexture   = rl.LoadRenderTexture
+	unloadRenderTexture = rl.UnloadRenderTexture
+)
+
+// bakedLayer is one stamp as it was when it was baked, to tell when it's changed. Stamps are changed by swapping in
+// new coords or palettes rather than editing them in place, so comparing the slice and palette is enough. A reloaded
+// spritesheet the same size keeps its texture, so the palette's generation tells when it's been redrawn
+type bakedLayer struct {
+	coords     *DrawCoord
+	count      int
+	generation int
+	palette    *Palette
+	texture    uint32
+	x, y       float32
+}
+
+// StampBake is a stamp, and any stamps laid over it, drawn once into a render texture. After that they're drawn as a
+// single quad, and only baked again when one of them changes
+type StampBake struct {
+	Texture rl.RenderTexture2D
+	layers  []bakedLayer
+}
+
+// Bake draws the stamps into the texture if they've changed since they were last baked, the rest placed relative to
+// the first. Drawing into a texture loses the camera, and ends any other texture being drawn into like a frame
+// capture, so everything's baked before drawing starts
+func (b *StampBake) Bake(stamps ...*Stamp) {
+	if b.stale(stamps) {
+		b.bake(stamps)
+	}
+}
+
+// Draw draws the stamps as they were last baked, at the base stamp's position. Nothing's drawn until they're baked
+func (b *StampBake) Draw(base *Stamp) {
+	if b.Texture.ID == 0 {
+		return
+	}
+	drawRenderTexture(b.Texture, rl.NewVector2(float32(int(base.LevelX)), float32(int(base.LevelY))))
+}
+
+// drawRenderTexture draws the whole of a render texture with its top left at the position
+func drawRenderTexture(target rl.RenderTexture2D, position rl.Vector2) {
+	width, height := float32(target.Texture.Width), float32(target.Texture.Height)
+	// Render textures are upside down, so the source is flipped back over
+	drawTextureRec(target.Texture, rl.NewRectangle(0, 0, width, -height), position, rl.White)
+}
+
+// stale returns true when the stamps aren't the ones in the texture
+func (b *StampBake) stale(stamps []*Stamp) bool {
+	base := stamps[0]
+	if b.Texture.ID == 0 || b.Texture.Texture.Width != int32(base.Width) || b.Texture.Texture.Height != int32(base.Height) {
+		return true
+	}
+	if len(b.layers) != len(stamps) {
+		return true
+	}
+	for i, stamp := range stamps {
+		if b.layers[i] != layerOf(base, stamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// bake draws the stamps into the texture, making a new one if the size has changed
+func (b *StampBake) bake(stamps []*Stamp) {
+	base := stamps[0]
+	width, height := int32(base.Width), int32(base.Height)
+	if b.Texture.Texture.Width != width || b.Texture.Texture.Height != height {
+		b.Release()
+	}
+	if b.Texture.ID == 0 {
+		b.Texture = loadRenderTexture(width, height)
+	}
+
+	b.layers = b.layers[:0]
+	beginTextureMode(b.Texture)
+	clearBackground(rl.Blank)
+	for _, stamp := range stamps {
+		layer := layerOf(base, stamp)
+		stamp.DrawAt(layer.x, layer.y)
+		b.layers = append(b.layers, layer)
+	}
+	endTextureMode()
+}
+
+// Release gives the texture back to the GPU. It's baked again if it's drawn after
+func (b *StampBake) Release() {
+	if b.Texture.ID != 0 {
+		unloadRenderTexture(b.Texture)
+	}
+	b.Texture = rl.RenderTexture2D{}
+	b.layers = nil
+}
+
+// BakeBuildings bakes every building that's changed since it was last drawn, and the preview of the building being
+// placed. It runs each frame before drawing starts
+func (e *Engine) BakeBuildings() {
+	for _, building := range e.Buildings() {
+		building.Bake()
+	}
+	if e.UI != nil {
+		e.UI.BakePreview()
+	}
+}
+
+// layerOf returns how a stamp would be baked over the base
+func layerOf(base, stamp *Stamp) bakedLayer {
+	layer := bakedLayer{count: len(stamp.DrawCoords), palette: stamp.Palette, x: stamp.LevelX - base.LevelX, y: stamp.LevelY - base.LevelY}
+	if layer.count > 0 {
+		layer.coords = &stamp.DrawCoords[0]
+	}
+	if stamp.Palette != nil {
+		layer.generation = stamp.Palette.Generation
+		layer.texture = stamp.Palette.Texture.ID
+	}
+	return layer
+}
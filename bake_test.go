@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGPU swaps raylib's drawing out for counters, so stamps can be drawn without a window. Targets lists the textures
+// drawn into, in order
+type fakeGPU struct {
+	bakes, draws, textures int
+	targets                []uint32
+}
+
+func useFakeGPU() (*fakeGPU, func()) {
+	gpu := &fakeGPU{}
+	saved := []interface{}{beginTextureMode, clearBackground, drawTextureRec, endTextureMode, loadRenderTexture, unloadRenderTexture, beginDrawing}
+	beginDrawing = func() {}
+	beginTextureMode = func(target rl.RenderTexture2D) {
+		gpu.bakes++
+		gpu.targets = append(gpu.targets, target.ID)
+	}
+	clearBackground = func(rl.Color) {}
+	drawTextureRec = func(rl.Texture2D, rl.Rectangle, rl.Vector2, rl.Color) { gpu.draws++ }
+	endTextureMode = func() {}
+	loadRenderTexture = func(width, height int32) rl.RenderTexture2D {
+		gpu.textures++
+		return rl.RenderTexture2D{ID: uint32(gpu.textures), Texture: rl.Texture2D{ID: 1000, Width: width, Height: height}}
+	}
+	unloadRenderTexture = func(rl.RenderTexture2D) { gpu.textures-- }
+	return gpu, func() {
+		beginTextureMode = saved[0].(func(rl.RenderTexture2D))
+		clearBackground = saved[1].(func(rl.Color))
+		drawTextureRec = saved[2].(func(rl.Texture2D, rl.Rectangle, rl.Vector2, rl.Color))
+		endTextureMode = saved[3].(func())
+		loadRenderTexture = saved[4].(func(int32, int32) rl.RenderTexture2D)
+		unloadRenderTexture = saved[5].(func(rl.RenderTexture2D))
+		beginDrawing = saved[6].(func())
+	}
+}
+
+// bakeCity places decorated houses along the street
+func bakeCity(count int) (*Engine, *Palette) {
+	engine := &Engine{RNG: NewRNG(1)}
+	palette := &Palette{Brushes: BrushMap(256, 208, 16, 16, 0, 0)}
+	for i := 0; i < count; i++ {
+		building := PlaceBuilding(engine, GetHouse(engine, palette), palette, float32(i*64))
+		for len(building.Decorations) < 3 {
+			Decorate(building)
+		}
+	}
+	return engine, palette
+}
+
+func TestStampBake(t *testing.T) {
+	gpu, restore := useFakeGPU()
+	defer restore()
+	engine, palette := bakeCity(1)
+	building := engine.Buildings()[0]
+
+	// Nothing's drawn until it's been baked
+	building.Draw()
+	assert.Equal(t, 0, gpu.draws)
+
+	building.Bake()
+	building.Draw()
+	assert.Equal(t, 1, gpu.bakes)
+	assert.Equal(t, 1, gpu.textures)
+	// Baking draws every brush, then the texture is drawn as one quad
+	assert.Equal(t, len(building.Stamp.DrawCoords)+3+1, gpu.draws)
+
+	// Drawing it again, even somewhere else, uses the texture as it is
+	gpu.draws = 0
+	building.Stamp.LevelX += 100
+	for _, d := range building.Decorations {
+		d.Stamp.LevelX += 100
+	}
+	building.Bake()
+	building.Draw()
+	assert.Equal(t, 1, gpu.bakes)
+	assert.Equal(t, 1, gpu.draws)
+
+	// It's baked again when the palette, the stamp or the decorations change
+	variant := *palette
+	building.Stamp.Palette = &variant
+	building.Bake()
+	assert.Equal(t, 2, gpu.bakes)
+	building.Stamp.DrawCoords = append([]DrawCoord{}, building.Stamp.DrawCoords...)
+	building.Bake()
+	assert.Equal(t, 3, gpu.bakes)
+	Decorate(building)
+	building.Bake()
+	assert.Equal(t, 4, gpu.bakes)
+	assert.Equal(t, 1, gpu.textures)
+
+	// Or when the spritesheet's reloaded, even into the same texture
+	reslice(&variant, variant.Texture)
+	building.Bake()
+	assert.Equal(t, 5, gpu.bakes)
+
+	// A new size needs a new texture
+	building.Stamp.Height += 16
+	building.Bake()
+	assert.Equal(t, 6, gpu.bakes)
+	assert.Equal(t, 1, gpu.textures)
+
+	building.Release()
+	assert.Equal(t, 0, gpu.textures)
+}
+
+// drawCity draws every building once, and reports how many draw calls each frame took
+func drawCity(b *testing.B, draw func(*Building)) {
+	gpu, restore := useFakeGPU()
+	defer restore()
+	engine, _ := bakeCity(200)
+	// The first frame bakes the city, which the frames after don't pay for
+	for _, building := range engine.Buildings() {
+		draw(building)
+	}
+	gpu.draws = 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, building := range engine.Buildings() {
+			draw(building)
+		}
+	}
+	b.ReportMetric(float64(gpu.draws)/float64(b.N), "draws/frame")
+}
+
+func BenchmarkStampDraw200(b *testing.B) {
+	drawCity(b, func(building *Building) {
+		building.Stamp.Draw()
+		for _, d := range building.Decorations {
+			d.Draw()
+		}
+	})
+}
+
+func BenchmarkBakedDraw200(b *testing.B) {
+	drawCity(b, func(building *Building) {
+		building.Bake()
+		building.drawBaked()
+	})
+}
@@ -2,9 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"math/rand"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -12,6 +9,8 @@ import (
 // Building provides an abstraction for buildings. Give it a stamp, or a collection of brushes
 //	and it's coordinate pairing,
 type Building struct {
+	Identity
+	Burning     bool
 	Cost        float64
 	Counter     int
 	Deleted     bool
@@ -19,9 +18,16 @@ type Building struct {
 	Effects     []func(*Building)
 	Engine      *Engine
 	Filepath    string
-	Palette     *Palette
-	Population  int
-	Stamp       *Stamp
+	// Name identifies the kind of building, so services like the fire station can be found by the engine
+	Name       string
+	Palette    *Palette
+	Population int
+	// Smoke puffs out of the chimney of any building people live in
+	Smoke *Emitter
+	Stamp *Stamp
+
+	// bake holds the stamp and its decorations drawn into a single texture
+	bake StampBake
 }
 
 // CanReap returns building.Deleted, designed to be toggled if a building is demolished
@@ -29,11 +35,45 @@ func (building *Building) CanReap() bool {
 	return building.Deleted
 }
 
+// Category files the building under buildings
+func (building *Building) Category() Category {
+	return CategoryBuilding
+}
+
 // Draw renders the stamp in the X,Y coordinates given
 func (building *Building) Draw() {
-	building.Stamp.Draw()
+	building.drawBaked()
+	if building.Smoke != nil {
+		building.Smoke.Draw()
+	}
+}
+
+// Bake bakes the stamp with its decorations over it into one texture, if they've changed since they were last baked
+func (building *Building) Bake() {
+	stamps := []*Stamp{building.Stamp}
 	for _, d := range building.Decorations {
-		d.Draw()
+		stamps = append(stamps, d.Stamp)
+	}
+	building.bake.Bake(stamps...)
+}
+
+// drawBaked draws the stamp with its decorations over it as one texture, as they were last baked
+func (building *Building) drawBaked() {
+	building.bake.Draw(building.Stamp)
+}
+
+// Release gives the baked texture back once the building's gone
+func (building *Building) Release() {
+	building.bake.Release()
+}
+
+// Render queues the building with its decorations baked in, then its smoke in front
+func (building *Building) Render(queue *RenderQueue) {
+	hitbox := building.GetHitbox()
+	z := hitbox.Y + hitbox.Height
+	queue.Push(LayerBuildings, z, building.drawBaked)
+	if building.Smoke != nil {
+		queue.Push(LayerParticles, z, building.Smoke.Draw)
 	}
 }
 
@@ -48,6 +88,19 @@ func (building *Building) Update() {
 	for _, e := range building.Effects {
 		e(building)
 	}
+
+	if building.Population > 0 {
+		if building.Smoke == nil {
+			building.Smoke = NewSmoke(building.Stamp.LevelX+building.Stamp.Width-12, building.Stamp.LevelY)
+			building.Smoke.RNG = building.Engine.Rand(StreamParticles)
+		}
+		// Everyone's got their heating on in the winter
+		building.Smoke.Rate = 0.05
+		if building.Engine != nil && building.Engine.Calendar.Season() == Winter {
+			building.Smoke.Rate = 0.2
+		}
+		building.Smoke.Update()
+	}
 }
 
 // GetHitbox returns a rectangle to represent the entity hitbox
@@ -65,12 +118,56 @@ func (building *Building) GetHitboxRight() rl.Rectangle {
 	return rl.NewRectangle(building.Stamp.LevelX, building.Stamp.LevelY, building.Stamp.Width+16, building.Stamp.Height)
 }
 
+// Catalog maps the name of each building to the function that puts it together
+var Catalog = map[string]func(*Engine, *Palette) *Building{
+	"house":       GetHouse,
+	"slum":        GetSlum,
+	"apartment":   GetApartment,
+	"church":      GetChurch,
+	"firestation": GetFireStation,
+	"militia":     GetMilitia,
+}
+
+// PlaceBuilding builds a new instance of the template building on the street at the given X coordinate, and adds it
+// to the engine. A new instance is created so the template can be reused without moving every building built from it
+func PlaceBuilding(engine *Engine, template *Building, palette *Palette, x float32) *Building {
+	// Buildings that come with their own palette, like some from mods, keep it
+	if template.Palette != nil {
+		palette = template.Palette
+	}
+	stamp := *template.Stamp
+	stamp.Palette = palette
+	stamp.LevelX = x
+	stamp.LevelY = float32(GroundLevel) - stamp.Height + 16
+	building := &Building{
+		Cost:       template.Cost,
+		Engine:     engine,
+		Filepath:   template.Filepath,
+		Name:       template.Name,
+		Palette:    template.Palette,
+		Population: template.Population,
+		Stamp:      &stamp,
+	}
+	// Any decorations come along, moved with the building
+	for _, d := range template.Decorations {
+		decoration := *d.Stamp
+		decoration.Palette = palette
+		decoration.LevelX += stamp.LevelX - template.Stamp.LevelX
+		decoration.LevelY += stamp.LevelY - template.Stamp.LevelY
+		building.Decorations = append(building.Decorations, Decoration{AttachedTo: building, Palette: d.Palette, Stamp: &decoration})
+	}
+	engine.PopulationMax += building.Population
+	engine.Add(building)
+	return building
+}
+
 // GetHouse puts together a 2 story building with a door
 func GetHouse(engine *Engine, palette *Palette) *Building {
-	door := rand.Intn(3)
-	window := rand.Intn(3)
+	rng := engine.Rand(StreamBuildings)
+	door := rng.Intn(3)
+	window := rng.Intn(3)
 	for window == door {
-		window = rand.Intn(3)
+		window = rng.Intn(3)
 	}
 	buildingStamp := &Stamp{Palette: palette, Width: 48, Height: 32}
 	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{75, 0, 0})                    // top left
@@ -80,13 +177,14 @@ func GetHouse(engine *Engine, palette *Palette) *Building {
 	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{79, 32, 0})                   // top right
 	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{111, 32, 16})                 // right
 	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{103, float32(door) * 16, 16}) // door randomized
-	if rand.Intn(3) == 1 {
+	if rng.Intn(3) == 1 {
 		buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{20, float32(window) * 16, 16}) // window randomized
 	}
 
 	return &Building{
 		Cost:       1,
 		Engine:     engine,
+		Name:       "house",
 		Population: 1,
 		Stamp:      buildingStamp,
 	}
@@ -105,6 +203,8 @@ func GetSlum(engine *Engine, palette *Palette) *Building {
 		Cost:       10,
 		Effects:    []func(*Building){Decorate},
 		Engine:     engine,
+		Filepath:   filepath,
+		Name:       "slum",
 		Population: 6,
 		Stamp:      buildingStamp,
 	}
@@ -119,6 +219,8 @@ func GetApartment(engine *Engine, palette *Palette) *Building {
 		Cost:       100,
 		Effects:    []func(*Building){Decorate},
 		Engine:     engine,
+		Filepath:   filepath,
+		Name:       "apartment",
 		Population: 12,
 		Stamp:      buildingStamp,
 	}
@@ -133,6 +235,51 @@ func GetChurch(engine *Engine, palette *Palette) *Building {
 		Cost:       300,
 		Effects:    []func(*Building){Decorate},
 		Engine:     engine,
+		Filepath:   filepath,
+		Name:       "church",
+		Population: 0,
+		Stamp:      buildingStamp,
+	}
+}
+
+// GetFireStation puts together a wide 2 story station with a pair of garage doors. Fires near a station get put out
+func GetFireStation(engine *Engine, palette *Palette) *Building {
+	buildingStamp := &Stamp{Palette: palette, Width: 64, Height: 32}
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{75, 0, 0})    // top left
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{107, 0, 16})  // left
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{77, 16, 0})   // top center
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{103, 16, 16}) // garage door
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{77, 32, 0})   // top center
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{103, 32, 16}) // garage door
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{79, 48, 0})   // top right
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{111, 48, 16}) // right
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{18, 0, 0})    // banner
+
+	return &Building{
+		Cost:       50,
+		Engine:     engine,
+		Name:       "firestation",
+		Population: 0,
+		Stamp:      buildingStamp,
+	}
+}
+
+// GetMilitia puts together a 3 story watchtower. Raiders that wander near the militia are run out of town
+func GetMilitia(engine *Engine, palette *Palette) *Building {
+	buildingStamp := &Stamp{Palette: palette, Width: 32, Height: 48}
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{75, 0, 0})    // top left
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{79, 16, 0})   // top right
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{107, 0, 16})  // left
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{111, 16, 16}) // right
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{107, 0, 32})  // left
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{103, 16, 32}) // door
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{20, 0, 16})   // window
+	buildingStamp.DrawCoords = append(buildingStamp.DrawCoords, DrawCoord{19, 16, 0})   // banner
+
+	return &Building{
+		Cost:       75,
+		Engine:     engine,
+		Name:       "militia",
 		Population: 0,
 		Stamp:      buildingStamp,
 	}
@@ -168,9 +315,10 @@ func (d *Decoration) GetHitbox() rl.Rectangle {
 
 // Decorate adds some decore to buildings in certain parameters
 func Decorate(building *Building) {
-	decorBrush := 139 + rand.Intn(5)
-	decorX := 16 * rand.Intn(int(building.Stamp.Width/16))
-	decorY := 16 * rand.Intn(int(building.Stamp.Height/16))
+	rng := building.Engine.Rand(StreamDecorations)
+	decorBrush := 139 + rng.Intn(5)
+	decorX := 16 * rng.Intn(int(building.Stamp.Width/16))
+	decorY := 16 * rng.Intn(int(building.Stamp.Height/16))
 	decorStamp := &Stamp{Palette: building.Stamp.Palette, LevelX: building.Stamp.LevelX, LevelY: building.Stamp.LevelY, Width: 16, Height: 16}
 	decorStamp.DrawCoords = append(decorStamp.DrawCoords, DrawCoord{decorBrush, float32(decorX), float32(decorY)}) // top left
 
@@ -180,21 +328,34 @@ func Decorate(building *Building) {
 
 // Layer represents a portion of the JSON file saved from Tiled
 type Layer struct {
-	Data    []int `json:"data"`
-	Height  int   `json:"height"`
-	Width   int   `json:"width"`
-	Opacity int   `json:"opacity"`
-	X       int   `json:"x"`
-	Y       int   `json:"y"`
+	Data    []int  `json:"data"`
+	Height  int    `json:"height"`
+	Width   int    `json:"width"`
+	Name    string `json:"name"`
+	Opacity int    `json:"opacity"`
+	// Type is "tilelayer" for layers of tiles. Object and image layers don't have any
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+// Tileset is a tileset a Tiled file draws from. It's either saved in its own file, named by Source, or embedded with
+// its Image
+type Tileset struct {
+	FirstGID int    `json:"firstgid"`
+	Image    string `json:"image"`
+	Name     string `json:"name"`
+	Source   string `json:"source"`
 }
 
 // Tiled represents the tiled file, or the JSON file exported from Tiled
 type Tiled struct {
-	Layers     []Layer `json:"layers"`
-	TileHeight int     `json:"tileheight"`
-	TileWidth  int     `json:"tilewidth"`
-	Width      int     `json:"width"`
-	Height     int     `json:"height"`
+	Layers     []Layer   `json:"layers"`
+	TileHeight int       `json:"tileheight"`
+	Tilesets   []Tileset `json:"tilesets"`
+	TileWidth  int       `json:"tilewidth"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
 }
 
 // GetStampFromTiledFile takes a filepath to a file saved from the popular tile map program, tiled:
@@ -244,14 +405,12 @@ func ParseTiled(t *Tiled) *Stamp {
 			counter++
 		}
 	}
-	fmt.Printf("Generated stamp from tiled:\n%v", stamp.DrawCoords)
-
 	return stamp
 }
 
 // loadTiled is a helper that unmarshals a file into a Tiled struct
 func loadTiled(filepath string) (*Tiled, error) {
-	file, err := ioutil.ReadFile(filepath)
+	file, err := ReadAsset(filepath)
 	if err != nil {
 		return &Tiled{}, err
 	}
@@ -30,6 +30,27 @@ func TestPaseTiled(t *testing.T) {
 	assert.Equal(t, DrawCoord{77, 16, 0}, stamp.DrawCoords[1])
 	assert.Equal(t, DrawCoord{77, 32, 0}, stamp.DrawCoords[2])
 
-	assert.Equal(t, stamp.DrawCoords[8], DrawCoord{107, 0, 16})
-	t.Errorf("%v", stamp.DrawCoords)
+	// The second row starts after the seven tiles of the roof
+	assert.Equal(t, DrawCoord{107, 0, 16}, stamp.DrawCoords[7])
+	assert.Equal(t, DrawCoord{109, 16, 16}, stamp.DrawCoords[8])
+}
+
+func TestPlaceBuildingCopiesTheTemplate(t *testing.T) {
+	engine := &Engine{RNG: NewRNG(1)}
+	template := GetHouse(engine, nil)
+	Decorate(template)
+	first := PlaceBuilding(engine, template, nil, 100)
+	second := PlaceBuilding(engine, template, nil, 300)
+
+	assert.Equal(t, float32(100), first.Stamp.LevelX)
+	assert.Equal(t, float32(300), second.Stamp.LevelX)
+	assert.Zero(t, template.Stamp.LevelX)
+	// The decorations move with each building, and belong to it
+	assert.Equal(t, template.Decorations[0].Stamp.LevelX+100, first.Decorations[0].Stamp.LevelX)
+	assert.Equal(t, template.Decorations[0].Stamp.LevelX+300, second.Decorations[0].Stamp.LevelX)
+	assert.Equal(t, first, first.Decorations[0].AttachedTo)
+
+	// Moving the template, like the preview following the cursor, leaves them where they were built
+	template.Stamp.LevelX = 500
+	assert.Equal(t, float32(100), first.Stamp.LevelX)
 }
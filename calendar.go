@@ -0,0 +1,120 @@
+package main
+
+import "fmt"
+
+const (
+	// DayLength is how many updates make up a day in the city, unless the calendar is configured otherwise
+	DayLength = 2000
+	// DaysPerSeason is how many days pass before the season turns
+	DaysPerSeason = 7
+)
+
+// Season represents the time of year
+type Season int
+
+const (
+	Spring Season = iota
+	Summer
+	Autumn
+	Winter
+)
+
+// String returns the name of the season
+func (s Season) String() string {
+	switch s {
+	case Summer:
+		return "Summer"
+	case Autumn:
+		return "Autumn"
+	case Winter:
+		return "Winter"
+	}
+	return "Spring"
+}
+
+// Palette returns which of the UI's city palettes the ground is drawn with during the season
+func (s Season) Palette() int {
+	switch s {
+	case Summer:
+		return 3
+	case Autumn:
+		return 4
+	case Winter:
+		return 1
+	}
+	return 2
+}
+
+// SeasonModifier adjusts the economy and weather for a season
+type SeasonModifier struct {
+	// Heating is the cost per building, per update, to keep the city warm
+	Heating float64
+	// Income multiplies the city's tax income
+	Income float64
+	// Weather are the odds of each kind of weather rolling during the season
+	Weather map[WeatherKind]int
+}
+
+// SeasonModifiers holds the modifiers for each season
+var SeasonModifiers = map[Season]SeasonModifier{
+	Spring: {Income: 1, Weather: map[WeatherKind]int{Clear: 35, Rainy: 35, Storm: 10, Fog: 15, AcidRain: 5}},
+	Summer: {Income: 1.1, Weather: map[WeatherKind]int{Clear: 60, Rainy: 10, Storm: 20, Fog: 5, AcidRain: 5}},
+	Autumn: {Income: 1, Weather: map[WeatherKind]int{Clear: 30, Rainy: 30, Storm: 10, Fog: 20, AcidRain: 10}},
+	Winter: {Heating: 0.0005, Income: 0.9, Weather: map[WeatherKind]int{Clear: 30, Snow: 50, Fog: 15, AcidRain: 5}},
+}
+
+// Calendar derives the time of day, day, season and year from the number of updates the city has been running
+type Calendar struct {
+	// DayLength is how many updates make up a day. Defaults to DayLength when unset
+	DayLength int
+	Ticks     int
+}
+
+// Tick advances the calendar by one update
+func (c *Calendar) Tick() {
+	c.Ticks++
+}
+
+// dayLength returns the configured length of a day
+func (c *Calendar) dayLength() int {
+	if c.DayLength <= 0 {
+		return DayLength
+	}
+	return c.DayLength
+}
+
+// Days returns the total number of whole days that have passed
+func (c *Calendar) Days() int {
+	return c.Ticks / c.dayLength()
+}
+
+// TimeOfDay returns how far through the day it is, from 0 at midnight up to 1
+func (c *Calendar) TimeOfDay() float64 {
+	return float64(c.Ticks%c.dayLength()) / float64(c.dayLength())
+}
+
+// IsNight returns true between sunset and sunrise
+func (c *Calendar) IsNight() bool {
+	timeOfDay := c.TimeOfDay()
+	return timeOfDay < Sunrise || timeOfDay > Sunset
+}
+
+// Day returns the day of the season, starting at 1
+func (c *Calendar) Day() int {
+	return c.Days()%DaysPerSeason + 1
+}
+
+// Season returns the current season
+func (c *Calendar) Season() Season {
+	return Season((c.Days() / DaysPerSeason) % 4)
+}
+
+// Year returns the current year, starting at 1
+func (c *Calendar) Year() int {
+	return c.Days()/(DaysPerSeason*4) + 1
+}
+
+// String returns the date for rendering in the UI
+func (c *Calendar) String() string {
+	return fmt.Sprintf("%v, Day %v, Year %v", c.Season(), c.Day(), c.Year())
+}
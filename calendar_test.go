@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar(t *testing.T) {
+	calendar := &Calendar{}
+	assert.Equal(t, 1, calendar.Day())
+	assert.Equal(t, Spring, calendar.Season())
+	assert.Equal(t, 1, calendar.Year())
+
+	calendar.Ticks = DayLength - 1
+	assert.Equal(t, 1, calendar.Day())
+	calendar.Tick()
+	assert.Equal(t, 2, calendar.Day())
+
+	calendar.Ticks = DayLength * DaysPerSeason
+	assert.Equal(t, 1, calendar.Day())
+	assert.Equal(t, Summer, calendar.Season())
+
+	calendar.Ticks = DayLength * DaysPerSeason * 3
+	assert.Equal(t, Winter, calendar.Season())
+	assert.Equal(t, "Winter, Day 1, Year 1", calendar.String())
+
+	calendar.Ticks = DayLength * DaysPerSeason * 4
+	assert.Equal(t, Spring, calendar.Season())
+	assert.Equal(t, 2, calendar.Year())
+}
+
+func TestTimeOfDay(t *testing.T) {
+	calendar := &Calendar{DayLength: 100}
+	assert.Equal(t, 0.0, calendar.TimeOfDay())
+	assert.True(t, calendar.IsNight())
+
+	calendar.Ticks = 50
+	assert.Equal(t, 0.5, calendar.TimeOfDay())
+	assert.False(t, calendar.IsNight())
+	assert.Equal(t, 1.0, Daylight(calendar.TimeOfDay()))
+
+	calendar.Ticks = 150
+	assert.Equal(t, 0.5, calendar.TimeOfDay())
+	assert.Equal(t, 1, calendar.Days())
+
+	assert.Equal(t, 0.0, Daylight(0))
+	assert.InDelta(t, 0.5, Daylight(Sunrise), 0.0001)
+	assert.Equal(t, SkyGradient[0].Color, SkyColor(0))
+	assert.Equal(t, SkyGradient[3].Color, SkyColor(0.5))
+}
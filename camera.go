@@ -0,0 +1,71 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// WorldWidth is how long the street is, in pixels. It's a few screens wide, and the camera scrolls along it
+var WorldWidth int
+
+// streetScreens is how many screens wide the street is
+const streetScreens = 3
+
+// Camera scrolls the view along the street. It only moves sideways, so screen and world Y are the same
+type Camera struct {
+	// Speed is how far the camera scrolls a second, in pixels
+	Speed float32
+	// Width is how wide the view is, usually the screen
+	Width float32
+	// X is the world position of the left edge of the screen
+	X float32
+}
+
+// NewCamera returns a camera over the middle of the street, where the taxi drops people off
+func NewCamera(width float32) *Camera {
+	camera := &Camera{Speed: 600, Width: width}
+	camera.ScrollTo(float32(WorldWidth)/2 - width/2)
+	return camera
+}
+
+// ScrollTo moves the camera, keeping the view on the street
+func (c *Camera) ScrollTo(x float32) {
+	if end := float32(WorldWidth) - c.Width; x > end {
+		x = end
+	}
+	if x < 0 {
+		x = 0
+	}
+	c.X = x
+}
+
+// Update scrolls the camera while the left or right keys are held
+func (c *Camera) Update() {
+	step := c.Speed * float32(Input.Frame.Elapsed.Seconds())
+	if Input.KeyDown(Keybindings["left"]) {
+		c.ScrollTo(c.X - step)
+	}
+	if Input.KeyDown(Keybindings["right"]) {
+		c.ScrollTo(c.X + step)
+	}
+}
+
+// View returns the part of the world on screen
+func (c *Camera) View() rl.Rectangle {
+	if c == nil {
+		return rl.NewRectangle(0, 0, float32(rl.GetScreenWidth()), float32(rl.GetScreenHeight()))
+	}
+	return rl.NewRectangle(c.X, 0, c.Width, float32(rl.GetScreenHeight()))
+}
+
+// WorldX converts an X position on screen, like the mouse's, to a position on the street
+func (c *Camera) WorldX(screenX int32) float32 {
+	if c == nil {
+		return float32(screenX)
+	}
+	return float32(screenX) + c.X
+}
+
+// Camera2D returns the camera for raylib's 2D mode, which draws the world scrolled into view
+func (c *Camera) Camera2D() rl.Camera2D {
+	return rl.NewCamera2D(rl.NewVector2(0, 0), rl.NewVector2(c.X, 0), 0, 1)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCameraStaysOnTheStreet(t *testing.T) {
+	defer func(width int) { WorldWidth = width }(WorldWidth)
+	WorldWidth = 3000
+
+	camera := NewCamera(1000)
+	assert.Equal(t, float32(1000), camera.X)
+	assert.Equal(t, float32(1250), camera.WorldX(250))
+
+	camera.ScrollTo(-50)
+	assert.Equal(t, float32(0), camera.X)
+	camera.ScrollTo(2500)
+	assert.Equal(t, float32(2000), camera.X)
+
+	// Without a camera the screen is the world
+	var none *Camera
+	assert.Equal(t, float32(250), none.WorldX(250))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// FrameCapture renders a frame into a texture, then shows it on screen, so the frame can also be read back as an
+// image. It works just the same from a hidden window, so hidden runs can save what they drew
+type FrameCapture struct {
+	Target rl.RenderTexture2D
+}
+
+// NewFrameCapture makes a capture the size of the screen
+func NewFrameCapture(width, height int32) *FrameCapture {
+	return &FrameCapture{Target: loadRenderTexture(width, height)}
+}
+
+// Begin sends everything drawn from here on into the capture. Nothing else can be drawn into a texture until it ends,
+// so anything that needs baking has to be baked first
+func (c *FrameCapture) Begin() {
+	beginTextureMode(c.Target)
+}
+
+// End stops capturing, and shows the captured frame on screen over the background
+func (c *FrameCapture) End(background rl.Color) {
+	endTextureMode()
+	clearBackground(background)
+	drawRenderTexture(c.Target, rl.NewVector2(0, 0))
+}
+
+// Image reads the last captured frame back from the GPU
+func (c *FrameCapture) Image() *image.RGBA {
+	img := rl.GetTextureData(c.Target.Texture)
+	defer rl.UnloadImage(img)
+	return screenImage(rl.GetImageData(img), int(img.Width), int(img.Height))
+}
+
+// Release gives the capture's texture back to the GPU
+func (c *FrameCapture) Release() {
+	unloadRenderTexture(c.Target)
+}
+
+// drawFrame bakes anything that's changed, then starts drawing and draws whatever push queues up, into the capture if
+// the frame's being saved. Baking comes first as it draws into textures of its own
+func drawFrame(engine *Engine, queue *RenderQueue, capture *FrameCapture, capturing bool, push func()) {
+	engine.BakeBuildings()
+	beginDrawing()
+	if capturing {
+		capture.Begin()
+	}
+	clearBackground(engine.Lightcycle)
+	push()
+	queue.Flush()
+	if capturing {
+		capture.End(engine.Lightcycle)
+	}
+}
+
+// screenImage turns pixels read from a render texture into an image. They come out bottom row first, and blending
+// leaves their alpha meaningless, so the rows are flipped and every pixel is made opaque like it is on screen
+func screenImage(pixels []rl.Color, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*width : (height-y)*width]
+		for x, pixel := range row {
+			img.SetRGBA(x, y, color.RGBA{R: pixel.R, G: pixel.G, B: pixel.B, A: 255})
+		}
+	}
+	return img
+}
+
+// SaveScreenshot writes the image to a PNG file, making its directory if need be
+func SaveScreenshot(img image.Image, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Timelapse collects a frame every so many in-game hours, to be written out as an animated GIF of the city growing
+type Timelapse struct {
+	// Every is how many ticks apart frames are captured
+	Every int
+	// Frames are the captured frames, shrunk by Scale and reduced to a GIF palette as they come in
+	Frames []*image.Paletted
+	// FrameDelay is how long each frame is shown for in the GIF, in hundredths of a second
+	FrameDelay int
+	Scale      int
+	// Start is the tick the timelapse started on
+	Start int
+
+	next int
+}
+
+// NewTimelapse starts a timelapse at the tick, capturing a frame every so many hours of a day that's dayLength ticks
+// long. The first frame is captured straight away
+func NewTimelapse(hours float64, dayLength, tick int) *Timelapse {
+	every := int(hours * float64(dayLength) / 24)
+	if every < 1 {
+		every = 1
+	}
+	return &Timelapse{Every: every, FrameDelay: 20, Scale: 2, Start: tick, next: tick}
+}
+
+// Due returns true when it's time to capture the next frame
+func (t *Timelapse) Due(tick int) bool {
+	return tick >= t.next
+}
+
+// Add captures a frame taken at the tick
+func (t *Timelapse) Add(img image.Image, tick int) {
+	t.Frames = append(t.Frames, quantize(shrink(img, t.Scale)))
+	t.next = tick + t.Every
+}
+
+// Encode writes the frames as a looping GIF. The last frame holds for a moment before it starts over
+func (t *Timelapse) Encode(w io.Writer) error {
+	if len(t.Frames) == 0 {
+		return fmt.Errorf("the timelapse has no frames")
+	}
+	animation := &gif.GIF{Image: t.Frames}
+	for range t.Frames {
+		animation.Delay = append(animation.Delay, t.FrameDelay)
+	}
+	animation.Delay[len(animation.Delay)-1] = t.FrameDelay * 5
+	return gif.EncodeAll(w, animation)
+}
+
+// Save writes the timelapse to a GIF file, making its directory if need be
+func (t *Timelapse) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// shrink scales the image down by a whole factor, keeping every scale'th pixel so pixel art stays crisp
+func shrink(img image.Image, scale int) image.Image {
+	if scale <= 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	small := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/scale, bounds.Dy()/scale))
+	for y := 0; y < small.Rect.Dy(); y++ {
+		for x := 0; x < small.Rect.Dx(); x++ {
+			small.Set(x, y, img.At(bounds.Min.X+x*scale, bounds.Min.Y+y*scale))
+		}
+	}
+	return small
+}
+
+// quantize reduces the image to a GIF palette. A frame with few enough colors keeps them exactly, and anything more
+// colorful, like the sky at dusk, is dithered to a standard palette
+func quantize(img image.Image) *image.Paletted {
+	bounds := img.Bounds()
+	colors := color.Palette{}
+	seen := make(map[color.RGBA]bool)
+	for y := bounds.Min.Y; y < bounds.Max.Y && len(colors) <= 256; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X && len(colors) <= 256; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			if !seen[c] {
+				seen[c] = true
+				colors = append(colors, c)
+			}
+		}
+	}
+
+	if len(colors) <= 256 {
+		paletted := image.NewPaletted(bounds, colors)
+		draw.Draw(paletted, bounds, img, bounds.Min, draw.Src)
+		return paletted
+	}
+	paletted := image.NewPaletted(bounds, palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+	return paletted
+}
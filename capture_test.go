@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenImage(t *testing.T) {
+	// Two rows, bottom first as they're read from a render texture, with the alpha blending leaves behind
+	pixels := []rl.Color{
+		rl.NewColor(1, 2, 3, 100), rl.NewColor(4, 5, 6, 100),
+		rl.NewColor(7, 8, 9, 200), rl.NewColor(10, 11, 12, 200),
+	}
+	img := screenImage(pixels, 2, 2)
+	assert.Equal(t, color.RGBA{7, 8, 9, 255}, img.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{10, 11, 12, 255}, img.RGBAAt(1, 0))
+	assert.Equal(t, color.RGBA{1, 2, 3, 255}, img.RGBAAt(0, 1))
+}
+
+func TestCaptureWithBakesPending(t *testing.T) {
+	gpu, restore := useFakeGPU()
+	defer restore()
+	capture := NewFrameCapture(640, 480)
+	engine, _ := bakeCity(2)
+	queue := &RenderQueue{}
+
+	drawFrame(engine, queue, capture, true, func() {
+		engine.Render(queue, rl.NewRectangle(0, 0, 640, 480))
+	})
+	// Both buildings are baked into their own textures before the frame's drawn into the capture, so the capture
+	// isn't cut short by a bake halfway through
+	assert.Equal(t, []uint32{2, 3, capture.Target.ID}, gpu.targets)
+	for _, building := range engine.Buildings() {
+		assert.NotZero(t, building.bake.Texture.ID)
+	}
+
+	// Drawing the next frame has nothing left to bake
+	gpu.targets = nil
+	drawFrame(engine, queue, capture, true, func() {
+		engine.Render(queue, rl.NewRectangle(0, 0, 640, 480))
+	})
+	assert.Equal(t, []uint32{capture.Target.ID}, gpu.targets)
+}
+
+func TestTimelapse(t *testing.T) {
+	// A frame every 6 hours of a 240 tick day
+	timelapse := NewTimelapse(6, 240, 100)
+	assert.Equal(t, 60, timelapse.Every)
+	assert.True(t, timelapse.Due(100))
+
+	frame := func(c color.RGBA) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+		img.SetRGBA(0, 0, color.RGBA{255, 255, 255, 255})
+		return img
+	}
+	timelapse.Add(frame(color.RGBA{40, 70, 140, 255}), 100)
+	assert.False(t, timelapse.Due(159))
+	assert.True(t, timelapse.Due(160))
+	timelapse.Add(frame(color.RGBA{200, 80, 80, 255}), 160)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, timelapse.Encode(buffer))
+	animation, err := gif.DecodeAll(buffer)
+	assert.NoError(t, err)
+	assert.Len(t, animation.Image, 2)
+	// Frames are shrunk by half, and keep their colors exactly
+	assert.Equal(t, image.Rect(0, 0, 4, 2), animation.Image[0].Bounds())
+	r, g, b, _ := animation.Image[1].At(1, 1).RGBA()
+	assert.Equal(t, []uint32{200, 80, 80}, []uint32{r >> 8, g >> 8, b >> 8})
+	assert.Equal(t, []int{20, 100}, animation.Delay)
+
+	assert.Error(t, (&Timelapse{}).Encode(&bytes.Buffer{}))
+}
+
+func TestQuantizeDithersColorfulFrames(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 8), uint8(y * 8), 128, 255})
+		}
+	}
+	paletted := quantize(img)
+	assert.LessOrEqual(t, len(paletted.Palette), 256)
+	assert.Equal(t, img.Bounds(), paletted.Bounds())
+}
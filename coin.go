@@ -1,8 +1,6 @@
 package main
 
 import (
-	"math/rand"
-
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -12,6 +10,7 @@ const (
 
 // Coin represents a coin drop
 type Coin struct {
+	Identity
 	Active         bool
 	Counter        float64
 	Done           bool
@@ -20,17 +19,19 @@ type Coin struct {
 	LevelX, LevelY float32
 	Velocity       float64
 	Sound          rl.Sound
-	Sprite         *Sprite
+	// Sparkles glint off the coin as it lands
+	Sparkles *Emitter
+	Sprite   *Sprite
+
+	soundPath string
 }
 
 // NewCoin generates a new coin at the coordinates provided
 func NewCoin(engine *Engine, dosh float64, levelX float32, levelY float32) *Coin {
-	var sound rl.Sound
 	soundPath := "assets/sounds/coin1.mp3"
-	if rand.Intn(2) == 1 {
+	if engine.Rand(StreamCoins).Intn(2) == 1 {
 		soundPath = "assets/sounds/coin2.mp3"
 	}
-	sound = rl.LoadSound(soundPath)
 
 	sprite := &Sprite{}
 	sprite.Init("assets/sprites/mega.png", 96, 32, 32, 32)
@@ -43,20 +44,41 @@ func NewCoin(engine *Engine, dosh float64, levelX float32, levelY float32) *Coin
 		Engine:   engine,
 		LevelX:   levelX,
 		LevelY:   levelY,
-		Sound:    sound,
+		Sound:    Assets.Sound(soundPath),
 		Sprite:   sprite,
 		Velocity: -10,
+
+		soundPath: soundPath,
 	}
 }
 
+// Release gives the coin's sprite and sound back to the asset manager
+func (coin *Coin) Release() {
+	coin.Sprite.Release()
+	Assets.ReleaseSound(coin.soundPath)
+}
+
 // CanReap returns Coin.Done
 func (coin *Coin) CanReap() bool {
 	return coin.Done
 }
 
+// Category files the coin under effects
+func (coin *Coin) Category() Category {
+	return CategoryEffect
+}
+
+// Snapshot remembers where the coin was before an update
+func (coin *Coin) Snapshot() {
+	coin.Sprite.Snapshot()
+}
+
 // Draw satisfies the entity interface
 func (coin *Coin) Draw() {
-	coin.Sprite.Draw()
+	coin.Sprite.Draw(coin.Engine.Alpha())
+	if coin.Sparkles != nil {
+		coin.Sparkles.Draw()
+	}
 }
 
 // Update performs the coin annimation
@@ -74,6 +96,9 @@ func (coin *Coin) Update() {
 			rl.PlaySound(coin.Sound)
 			coin.Engine.Dosh += coin.Dosh
 			coin.Active = false
+			coin.Sparkles = NewSparkles(coin.Sprite.LevelX+8, coin.Sprite.LevelY+16)
+			coin.Sparkles.RNG = coin.Engine.Rand(StreamParticles)
+			coin.Sparkles.Burst(12)
 		} else {
 			coin.Sprite.LevelY += float32(coin.Velocity)
 		}
@@ -87,10 +112,11 @@ func (coin *Coin) Update() {
 			coin.Done = true
 		}
 		coin.Counter += 0.25
+		coin.Sparkles.Update()
 	}
 }
 
 // GetHitbox returns a rectangle to represent the entity hitbox
 func (coin *Coin) GetHitbox() rl.Rectangle {
-	return rl.NewRectangle(coin.Sprite.XPos, coin.Sprite.YPos, coin.Sprite.Width, coin.Sprite.Height)
+	return rl.NewRectangle(coin.Sprite.LevelX, coin.Sprite.LevelY, coin.Sprite.Width, coin.Sprite.Height)
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// SkyKey is a point in the day where the sky is a known color. Colors in between keys are blended
+type SkyKey struct {
+	Color rl.Color
+	// Time is the time of day from 0 (midnight) to 1 (the following midnight)
+	Time float64
+}
+
+// SkyGradient runs the sky from night, through dawn, day and dusk, then back to night
+var SkyGradient = []SkyKey{
+	{rl.NewColor(22, 24, 48, 255), 0},
+	{rl.NewColor(22, 24, 48, 255), 0.2},
+	{rl.NewColor(236, 150, 110, 255), 0.27},
+	{rl.NewColor(190, 220, 245, 255), 0.35},
+	{rl.NewColor(190, 220, 245, 255), 0.65},
+	{rl.NewColor(226, 118, 90, 255), 0.73},
+	{rl.NewColor(22, 24, 48, 255), 0.8},
+	{rl.NewColor(22, 24, 48, 255), 1},
+}
+
+const (
+	// Sunrise and Sunset are the times of day the sun crosses the horizon
+	Sunrise = 0.25
+	Sunset  = 0.75
+)
+
+// SkyColor returns the color of the sky at the given time of day
+func SkyColor(timeOfDay float64) rl.Color {
+	for i := 1; i < len(SkyGradient); i++ {
+		from, to := SkyGradient[i-1], SkyGradient[i]
+		if timeOfDay > to.Time {
+			continue
+		}
+		blend := (timeOfDay - from.Time) / (to.Time - from.Time)
+		return rl.NewColor(
+			uint8(float64(from.Color.R)+(float64(to.Color.R)-float64(from.Color.R))*blend),
+			uint8(float64(from.Color.G)+(float64(to.Color.G)-float64(from.Color.G))*blend),
+			uint8(float64(from.Color.B)+(float64(to.Color.B)-float64(from.Color.B))*blend),
+			255,
+		)
+	}
+	return SkyGradient[len(SkyGradient)-1].Color
+}
+
+// Daylight returns how bright it is at the given time of day, from 0 at night to 1 during the day
+func Daylight(timeOfDay float64) float64 {
+	// Light fades in and out over the hour either side of the horizon
+	fade := 0.05
+	switch {
+	case timeOfDay < Sunrise-fade || timeOfDay > Sunset+fade:
+		return 0
+	case timeOfDay < Sunrise+fade:
+		return (timeOfDay - (Sunrise - fade)) / (fade * 2)
+	case timeOfDay > Sunset-fade:
+		return ((Sunset + fade) - timeOfDay) / (fade * 2)
+	}
+	return 1
+}
+
+// DayNight renders the sun and moon, and lights the city up at night with windows and street lamps
+type DayNight struct {
+	Engine *Engine
+	// LampSpacing is the distance in pixels between each street lamp
+	LampSpacing int
+}
+
+// NewDayNight returns the day/night renderer for the engine
+func NewDayNight(engine *Engine) *DayNight {
+	return &DayNight{Engine: engine, LampSpacing: 160}
+}
+
+// DrawSky renders the sun during the day and the moon during the night, arcing over the city
+func (d *DayNight) DrawSky() {
+	timeOfDay := d.Engine.Calendar.TimeOfDay()
+	color := rl.NewColor(255, 220, 120, 255)
+	progress := (timeOfDay - Sunrise) / (Sunset - Sunrise)
+	if timeOfDay < Sunrise || timeOfDay > Sunset {
+		// Night wraps around midnight, so shift it along to have the moon rise as the sun sets
+		color = rl.NewColor(230, 230, 255, 255)
+		progress = math.Mod(timeOfDay-Sunset+1, 1) / (1 - (Sunset - Sunrise))
+	}
+
+	horizon := float64(GroundLevel)
+	x := progress * float64(rl.GetScreenWidth())
+	y := horizon - math.Sin(progress*math.Pi)*horizon*0.8
+	rl.DrawCircle(int32(x), int32(y), 24, color)
+}
+
+// DrawNight darkens the city as the light fades, then lights up building windows and street lamps over the top
+func (d *DayNight) DrawNight() {
+	darkness := 1 - Daylight(d.Engine.Calendar.TimeOfDay())
+	view := d.Engine.Camera.View()
+	if darkness > 0 {
+		rl.DrawRectangleRec(view, rl.NewColor(10, 10, 40, uint8(140*darkness)))
+
+		for _, building := range d.Engine.Buildings() {
+			drawWindows(building, darkness)
+		}
+	}
+	for x := d.LampSpacing / 2; x < WorldWidth; x += d.LampSpacing {
+		// Lamps glow a little past where they stand, so keep the ones just off screen too
+		if float32(x) < view.X-64 || float32(x) > view.X+view.Width+64 {
+			continue
+		}
+		drawLamp(float32(x), darkness)
+	}
+}
+
+// drawWindows lights up some of the building's windows. Which windows are lit is picked from the building's position
+// so the same ones stay lit from one frame to the next
+func drawWindows(building *Building, darkness float64) {
+	hitbox := building.GetHitbox()
+	// Skip the roof and the ground floor, where the door is
+	for y := float32(16); y < hitbox.Height-16; y += 16 {
+		for x := float32(0); x < hitbox.Width; x += 16 {
+			if (int(hitbox.X)/16*31+int(x)/16*7+int(y)/16*13)%3 != 0 {
+				continue
+			}
+			window := rl.NewRectangle(hitbox.X+x+5, hitbox.Y+y+5, 6, 6)
+			rl.DrawRectangleRec(window, rl.NewColor(255, 210, 110, uint8(230*darkness)))
+		}
+	}
+}
+
+// drawLamp renders a street lamp at the given X coordinate, glowing as it gets dark
+func drawLamp(x float32, darkness float64) {
+	ground := float32(GroundLevel)
+	rl.DrawRectangleRec(rl.NewRectangle(x, ground-28, 2, 28), rl.DarkGray)
+	rl.DrawRectangleRec(rl.NewRectangle(x-3, ground-31, 8, 3), rl.DarkGray)
+	if darkness <= 0 {
+		return
+	}
+	rl.DrawCircleGradient(int32(x+1), int32(ground-28), 40, rl.NewColor(255, 220, 140, uint8(120*darkness)), rl.NewColor(255, 220, 140, 0))
+	rl.DrawRectangleRec(rl.NewRectangle(x-2, ground-28, 6, 2), rl.NewColor(255, 230, 160, uint8(255*darkness)))
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// DisasterFrequency is the player's setting for how often disasters strike the city
+type DisasterFrequency int
+
+const (
+	DisastersOff DisasterFrequency = iota
+	DisastersRare
+	DisastersNormal
+	DisastersFrequent
+)
+
+// String returns a label suitable for rendering on a button
+func (f DisasterFrequency) String() string {
+	switch f {
+	case DisastersOff:
+		return "off"
+	case DisastersRare:
+		return "rare"
+	case DisastersFrequent:
+		return "frequent"
+	}
+	return "normal"
+}
+
+// Next cycles through the frequencies, wrapping back around to off
+func (f DisasterFrequency) Next() DisasterFrequency {
+	return (f + 1) % (DisastersFrequent + 1)
+}
+
+// odds returns the X in a 1 in X chance, rolled every update, that a disaster strikes. 0 means never
+func (f DisasterFrequency) odds() int {
+	switch f {
+	case DisastersRare:
+		return int(rate) * 10
+	case DisastersNormal:
+		return int(rate) * 5
+	case DisastersFrequent:
+		return int(rate) * 2
+	}
+	return 0
+}
+
+// strikes rolls the frequency's odds, returning true if a disaster strikes this update
+func (f DisasterFrequency) strikes(rng *rand.Rand) bool {
+	odds := f.odds()
+	return odds > 0 && rng.Intn(odds) == 1
+}
+
+// Disasters is an engine effect that rolls for fires, floods and raids based on the engine's DisasterFrequency
+func Disasters(engine *Engine) {
+	rng := engine.Rand(StreamDisasters)
+	if !engine.DisasterFrequency.strikes(rng) {
+		return
+	}
+
+	buildings := engine.Buildings()
+	switch rng.Intn(3) {
+	case 0:
+		if len(buildings) > 0 {
+			Ignite(engine, buildings[rng.Intn(len(buildings))])
+		}
+	case 1:
+		// Floods only come with heavy rain
+		if engine.Weather != nil && engine.Weather.Rain.IsHeavy() {
+			engine.Spawn(NewFlood(engine))
+		}
+	case 2:
+		// Raiders only bother showing up once there's something to take
+		if len(buildings) > 0 {
+			for _, raider := range NewRaidParty(engine, 2+rng.Intn(3)) {
+				engine.Spawn(raider)
+			}
+		}
+	}
+}
+
+// nearService returns true if a building offering the given service is within reach of the target rectangle
+func nearService(engine *Engine, service string, target rl.Rectangle) bool {
+	reach := rl.NewRectangle(target.X-serviceReach, target.Y, target.Width+(serviceReach*2), target.Height)
+	for _, building := range engine.BuildingsIn(reach) {
+		if building.Name == service {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceReach is how far in pixels to either side of a response building its crews will travel
+const serviceReach = 400
+
+// Fire burns a building down unless a fire station is close enough to put it out
+type Fire struct {
+	Identity
+	Building *Building
+	Counter  int
+	Done     bool
+	Engine   *Engine
+	Flames   *Emitter
+	// Heat climbs while the fire burns unchecked, and falls while it's being fought. At 0 the fire is out
+	Heat float64
+}
+
+// Ignite sets a building on fire, unless it's already burning
+func Ignite(engine *Engine, building *Building) {
+	if building.Burning || building.Deleted {
+		return
+	}
+	building.Burning = true
+	hitbox := building.GetHitbox()
+	flames := NewFlames(rl.NewRectangle(hitbox.X, hitbox.Y, hitbox.Width, 4))
+	flames.RNG = engine.Rand(StreamParticles)
+	engine.Spawn(&Fire{Building: building, Engine: engine, Flames: flames, Heat: 1})
+}
+
+// CanReap returns true once the fire is out, or has nothing left to burn
+func (fire *Fire) CanReap() bool {
+	return fire.Done
+}
+
+// Category files the fire under effects
+func (fire *Fire) Category() Category {
+	return CategoryEffect
+}
+
+// Draw renders a glow over the building, and the flames licking up off its roof
+func (fire *Fire) Draw() {
+	fire.drawGlow()
+	fire.Flames.Draw()
+}
+
+// drawGlow tints the building the color of the fire, brighter the hotter it burns
+func (fire *Fire) drawGlow() {
+	rl.DrawRectangleRec(fire.Building.GetHitbox(), rl.NewColor(255, 60, 0, uint8(math.Min(fire.Heat*30, 120))))
+}
+
+// Render queues the glow over the building, and the flames in front of everything on the street
+func (fire *Fire) Render(queue *RenderQueue) {
+	hitbox := fire.Building.GetHitbox()
+	queue.Push(LayerDecorations, hitbox.Y+hitbox.Height, fire.drawGlow)
+	queue.Push(LayerParticles, hitbox.Y+hitbox.Height, fire.Flames.Draw)
+}
+
+// Update burns the building, spreads to any neighbors, and burns the building down if the fire gets too hot
+func (fire *Fire) Update() {
+	if fire.Building.Deleted {
+		fire.Done = true
+		return
+	}
+
+	if nearService(fire.Engine, "firestation", fire.Building.GetHitbox()) {
+		fire.Heat -= 0.01
+	} else {
+		fire.Heat += 0.001
+	}
+
+	if fire.Heat <= 0 {
+		fire.Building.Burning = false
+		fire.Done = true
+		return
+	}
+	// The hotter it burns, the bigger the flames
+	fire.Flames.Rate = fire.Heat * 2
+	fire.Flames.Update()
+
+	// Roughly every 3 seconds we give the fire a chance to jump to the buildings next door
+	if fire.Counter%(TickRate*3) == 0 && fire.Engine.Rand(StreamDisasters).Intn(3) == 1 {
+		neighbours := append(fire.Engine.BuildingsIn(fire.Building.GetHitboxLeft()), fire.Engine.BuildingsIn(fire.Building.GetHitboxRight())...)
+		for _, building := range neighbours {
+			if building != fire.Building {
+				Ignite(fire.Engine, building)
+			}
+		}
+	}
+
+	// A fire left burning this hot takes the building with it
+	if fire.Heat >= 3 {
+		fire.Building.Deleted = true
+		fire.Engine.PopulationMax -= fire.Building.Population
+		fire.Done = true
+	}
+	fire.Counter++
+}
+
+// GetHitbox returns the hitbox of the building that's burning
+func (fire *Fire) GetHitbox() rl.Rectangle {
+	return fire.Building.GetHitbox()
+}
+
+// Flood raises the water at street level after heavy rain, costing the city in repairs
+type Flood struct {
+	Identity
+	Counter  int
+	Done     bool
+	Duration int
+	Engine   *Engine
+	Level    float32
+}
+
+// NewFlood starts the water rising
+func NewFlood(engine *Engine) *Flood {
+	return &Flood{Duration: TickRate * (30 + engine.Rand(StreamDisasters).Intn(30)), Engine: engine}
+}
+
+// CanReap returns true once the water has receded
+func (flood *Flood) CanReap() bool {
+	return flood.Done
+}
+
+// Category files the flood under effects
+func (flood *Flood) Category() Category {
+	return CategoryEffect
+}
+
+// Draw renders the flood water over the street. Weather is drawn over the screen rather than scrolled with the street,
+// and the flood covers all of it
+func (flood *Flood) Draw() {
+	top := float32(GroundLevel) + 16 - flood.Level
+	rl.DrawRectangleRec(rl.NewRectangle(0, top, float32(rl.GetScreenWidth()), flood.Level), rl.NewColor(40, 70, 140, 160))
+}
+
+// Render queues the flood water over the street and everyone on it
+func (flood *Flood) Render(queue *RenderQueue) {
+	queue.Push(LayerWeather, float32(GroundLevel), flood.Draw)
+}
+
+// Update rises the water for the first half of the flood, then drains it for the second.
+// While the water is high, every building costs a little in repairs
+func (flood *Flood) Update() {
+	peak := float32(24)
+	if flood.Counter < flood.Duration/2 {
+		flood.Level = peak * float32(flood.Counter) / float32(flood.Duration/2)
+	} else {
+		flood.Level = peak * float32(flood.Duration-flood.Counter) / float32(flood.Duration/2)
+	}
+
+	if flood.Level > 8 {
+		flood.Engine.Dosh -= 0.001 * float64(len(flood.Engine.Buildings()))
+		if flood.Engine.Dosh < 0 {
+			flood.Engine.Dosh = 0
+		}
+	}
+
+	flood.Counter++
+	if flood.Counter >= flood.Duration {
+		flood.Done = true
+	}
+}
+
+// GetHitbox returns the area under water
+func (flood *Flood) GetHitbox() rl.Rectangle {
+	return rl.NewRectangle(0, float32(GroundLevel)+16-flood.Level, float32(WorldWidth), flood.Level)
+}
+
+// Raider walks in from off-screen, makes for a building and robs the city blind unless the militia runs them off
+type Raider struct {
+	Identity
+	Done    bool
+	Engine  *Engine
+	Fleeing bool
+	// Loot is how much dosh the raider has made off with
+	Loot    float64
+	Sprite  Sprite
+	TargetX float32
+}
+
+// NewRaidParty spawns a group of raiders just off one end of the street, each with their eyes on a building
+func NewRaidParty(engine *Engine, size int) []*Raider {
+	rng := engine.Rand(StreamDisasters)
+	buildings := engine.Buildings()
+	startX := float32(-32)
+	if rng.Intn(2) == 1 {
+		startX = float32(WorldWidth)
+	}
+
+	raiders := []*Raider{}
+	for i := 0; i < size; i++ {
+		target := buildings[rng.Intn(len(buildings))].GetHitbox()
+		raider := &Raider{Engine: engine, TargetX: target.X + float32(rng.Intn(int(target.Width)))}
+		variant := rng.Intn(4)
+		raider.Sprite.Init("assets/sprites/mega.png", 0, 864+float32(32*variant), 32, 32)
+		raider.Sprite.Clips = MegaClips(fmt.Sprintf("person%v", variant))
+		raider.Sprite.Color = rl.NewColor(200, 80, 80, 255)
+		raider.Sprite.Speed = 2
+		// Stagger the party so they don't walk in single file on top of each other
+		raider.Sprite.LevelX = startX - float32(i*24)
+		if startX > 0 {
+			raider.Sprite.LevelX = startX + float32(i*24)
+		}
+		raider.Sprite.LevelY = float32(GroundLevel)
+		raiders = append(raiders, raider)
+	}
+	return raiders
+}
+
+// CanReap returns true once the raider has left the screen
+func (raider *Raider) CanReap() bool {
+	return raider.Done
+}
+
+// Release gives the raider's sprite back to the asset manager
+func (raider *Raider) Release() {
+	raider.Sprite.Release()
+}
+
+// Category files the raider under effects
+func (raider *Raider) Category() Category {
+	return CategoryEffect
+}
+
+// Snapshot remembers where the raider was before an update
+func (raider *Raider) Snapshot() {
+	raider.Sprite.Snapshot()
+}
+
+// Draw renders the raider's sprite to the screen
+func (raider *Raider) Draw() {
+	raider.Sprite.Draw(raider.Engine.Alpha())
+}
+
+// Render queues the raider alongside the city's people
+func (raider *Raider) Render(queue *RenderQueue) {
+	hitbox := raider.GetHitbox()
+	queue.Push(LayerPeople, hitbox.Y+hitbox.Height, raider.Draw)
+}
+
+// Update walks the raider to their target, steals what they can, then runs for the edge of the screen
+func (raider *Raider) Update() {
+	raider.Sprite.Play("walk")
+	if !raider.Fleeing && nearService(raider.Engine, "militia", raider.GetHitbox()) {
+		// The militia sends them packing empty handed
+		raider.Fleeing = true
+		raider.Loot = 0
+	}
+
+	if raider.Fleeing {
+		// Run for whichever edge is closest
+		if raider.Sprite.LevelX < float32(WorldWidth/2) {
+			raider.Sprite.Reversed = true
+			raider.Sprite.LevelX -= float32(raider.Sprite.Speed * 2)
+		} else {
+			raider.Sprite.Reversed = false
+			raider.Sprite.LevelX += float32(raider.Sprite.Speed * 2)
+		}
+		if raider.Sprite.LevelX < -raider.Sprite.Width || raider.Sprite.LevelX > float32(WorldWidth) {
+			raider.Done = true
+		}
+	} else {
+		distance := raider.TargetX - raider.Sprite.LevelX
+		if math.Abs(float64(distance)) <= float64(raider.Sprite.Speed) {
+			raider.Loot = math.Min(raider.Engine.Dosh*0.1, 50)
+			raider.Engine.Dosh -= raider.Loot
+			raider.Fleeing = true
+		} else if distance > 0 {
+			raider.Sprite.Reversed = false
+			raider.Sprite.LevelX += float32(raider.Sprite.Speed)
+		} else {
+			raider.Sprite.Reversed = true
+			raider.Sprite.LevelX -= float32(raider.Sprite.Speed)
+		}
+	}
+	raider.Sprite.Update()
+}
+
+// GetHitbox returns a rectangle to represent the entity hitbox
+func (raider *Raider) GetHitbox() rl.Rectangle {
+	return rl.NewRectangle(raider.Sprite.LevelX, raider.Sprite.LevelY, raider.Sprite.Width, raider.Sprite.Height)
+}
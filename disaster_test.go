@@ -0,0 +1,181 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fireAt returns the fire burning the building, if there is one
+func fireAt(engine *Engine, building *Building) *Fire {
+	for _, entity := range engine.ByCategory(CategoryEffect) {
+		if fire, ok := entity.(*Fire); ok && fire.Building == building {
+			return fire
+		}
+	}
+	return nil
+}
+
+func TestFireSpreadsToNeighbours(t *testing.T) {
+	engine := &Engine{RNG: NewRNG(1)}
+	house := PlaceBuilding(engine, GetHouse(engine, nil), nil, 100)
+	nextDoor := PlaceBuilding(engine, GetHouse(engine, nil), nil, 100+house.Stamp.Width)
+	farAway := PlaceBuilding(engine, GetHouse(engine, nil), nil, 100+house.Stamp.Width*4)
+
+	Ignite(engine, house)
+	engine.Update()
+	assert.True(t, house.Burning)
+	assert.NotNil(t, fireAt(engine, house))
+
+	// The fire gets a chance to jump every few seconds, so given a minute it should have
+	for i := 0; i < TickRate*60 && !nextDoor.Burning; i++ {
+		engine.Update()
+	}
+	assert.True(t, nextDoor.Burning)
+	assert.NotNil(t, fireAt(engine, nextDoor))
+	assert.False(t, farAway.Burning)
+}
+
+func TestFireBurnsBuildingDown(t *testing.T) {
+	engine := &Engine{RNG: NewRNG(1)}
+	house := PlaceBuilding(engine, GetHouse(engine, nil), nil, 100)
+	populationMax := engine.PopulationMax
+
+	Ignite(engine, house)
+	engine.Update()
+	fire := fireAt(engine, house)
+	fire.Heat = 2.999
+	engine.Update()
+
+	assert.True(t, house.Deleted)
+	assert.Equal(t, populationMax-house.Population, engine.PopulationMax)
+	assert.True(t, fire.Done)
+	assert.Nil(t, fireAt(engine, house))
+}
+
+func TestFireStationPutsFiresOut(t *testing.T) {
+	engine := &Engine{RNG: NewRNG(1)}
+	house := PlaceBuilding(engine, GetHouse(engine, nil), nil, 100)
+	PlaceBuilding(engine, GetFireStation(engine, nil), nil, 100+house.Stamp.Width*3)
+
+	Ignite(engine, house)
+	engine.Update()
+	fire := fireAt(engine, house)
+	for i := 0; i < 200 && !fire.Done; i++ {
+		engine.Update()
+	}
+
+	assert.True(t, fire.Done)
+	assert.False(t, house.Burning)
+	assert.False(t, house.Deleted)
+}
+
+func TestFloodsNeedHeavyRain(t *testing.T) {
+	floods := func(density float64) int {
+		engine := &Engine{DisasterFrequency: DisastersFrequent, RNG: NewRNG(1), Weather: &Weather{Rain: &Rain{Density: density}}}
+		count := 0
+		for i := 0; i < 200000; i++ {
+			Disasters(engine)
+		}
+		for _, entity := range engine.spawns {
+			if _, ok := entity.(*Flood); ok {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Zero(t, floods(0.8))
+	assert.NotZero(t, floods(4))
+}
+
+func TestFloodRisesAndRecedes(t *testing.T) {
+	engine := &Engine{Dosh: 100, RNG: NewRNG(1)}
+	PlaceBuilding(engine, GetHouse(engine, nil), nil, 100)
+	flood := NewFlood(engine)
+
+	peak := float32(0)
+	for !flood.Done {
+		flood.Update()
+		if flood.Level > peak {
+			peak = flood.Level
+		}
+	}
+	assert.InDelta(t, 24, peak, 0.5)
+	assert.InDelta(t, 0, flood.Level, 0.5)
+	assert.Less(t, engine.Dosh, 100.0)
+}
+
+// newRaider returns a raider on the street without a sprite sheet, walking towards the target
+func newRaider(engine *Engine, x, targetX float32) *Raider {
+	raider := &Raider{Engine: engine, TargetX: targetX}
+	raider.Sprite.LevelX, raider.Sprite.LevelY = x, float32(GroundLevel)
+	raider.Sprite.Width, raider.Sprite.Height = 32, 32
+	raider.Sprite.Speed = 2
+	return raider
+}
+
+func TestRaidersStealAndFlee(t *testing.T) {
+	defer func(width int) { WorldWidth = width }(WorldWidth)
+	WorldWidth = 1000
+	engine := &Engine{Dosh: 100, RNG: NewRNG(1)}
+	raider := newRaider(engine, -32, 200)
+
+	for i := 0; i < 1000 && !raider.Fleeing; i++ {
+		raider.Update()
+	}
+	assert.True(t, raider.Fleeing)
+	assert.Equal(t, 10.0, raider.Loot)
+	assert.Equal(t, 90.0, engine.Dosh)
+
+	// They make for the nearest edge, and they're gone once they're past it
+	for i := 0; i < 1000 && !raider.Done; i++ {
+		raider.Update()
+	}
+	assert.True(t, raider.Done)
+	assert.True(t, raider.CanReap())
+	assert.Less(t, raider.Sprite.LevelX, float32(0))
+}
+
+func TestMilitiaDrivesRaidersOff(t *testing.T) {
+	defer func(width int) { WorldWidth = width }(WorldWidth)
+	WorldWidth = 1000
+	engine := &Engine{Dosh: 100, RNG: NewRNG(1)}
+	PlaceBuilding(engine, GetMilitia(engine, nil), nil, 800)
+	raider := newRaider(engine, 600, 820)
+	raider.Loot = 5
+
+	raider.Update()
+	assert.True(t, raider.Fleeing)
+	assert.Zero(t, raider.Loot)
+	assert.Equal(t, 100.0, engine.Dosh)
+
+	for i := 0; i < 1000 && !raider.Done; i++ {
+		raider.Update()
+	}
+	assert.True(t, raider.Done)
+	assert.Greater(t, raider.Sprite.LevelX, float32(WorldWidth))
+}
+
+func TestDisasterFrequency(t *testing.T) {
+	strikes := func(frequency DisasterFrequency) int {
+		rng := NewRNG(1).Stream(StreamDisasters)
+		count := 0
+		for i := 0; i < 1000000; i++ {
+			if frequency.strikes(rng) {
+				count++
+			}
+		}
+		return count
+	}
+
+	assert.Zero(t, strikes(DisastersOff))
+	rare, normal, frequent := strikes(DisastersRare), strikes(DisastersNormal), strikes(DisastersFrequent)
+	assert.Less(t, rare, normal)
+	assert.Less(t, normal, frequent)
+	// Frequent disasters strike five times as often as rare ones, give or take the luck of the draw
+	assert.InDelta(t, 5, float64(frequent)/float64(rare), 1.5)
+
+	assert.Equal(t, DisastersRare, DisastersOff.Next())
+	assert.Equal(t, DisastersOff, DisastersFrequent.Next())
+	assert.Equal(t, "frequent", DisastersFrequent.String())
+}
@@ -1,103 +1,388 @@
 package main
 
 import (
-	"math"
-	"reflect"
+	"math/rand"
+	"sort"
+	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+const (
+	// TickRate is how many times a second the simulation updates, regardless of the frame rate
+	TickRate = 60
+	// TickDuration is how much time a single simulation update covers
+	TickDuration = time.Second / TickRate
+	// maxFrameTime caps how far a single slow frame can push the simulation, so we catch up instead of spiralling
+	maxFrameTime = time.Second / 4
+)
+
+// TimeScale is how many times faster than real time the simulation runs
+type TimeScale int
+
+const (
+	Paused  TimeScale = 0
+	Normal  TimeScale = 1
+	Fast    TimeScale = 2
+	Fastest TimeScale = 4
+)
+
 // Engine holds the game state
 type Engine struct {
-	BuildingBoxes []rl.Rectangle
-	Counter       int
-	Dosh          float64
-	Effects       []func(*Engine)
+	Calendar Calendar
+	// Camera is the part of the street that's on screen
+	Camera *Camera
+	// DisasterFrequency controls how often the Disasters effect strikes
+	DisasterFrequency DisasterFrequency
+	Dosh              float64
+	Effects           []func(*Engine)
+	// Entities are every entity in the engine, in the order they're updated and drawn. Use Add and Remove to change them,
+	// or Spawn and Despawn while a tick is running
 	Entities      []Entity
 	Lightcycle    rl.Color
-	Pi            float64
 	Population    int
 	PopulationMax int
-	Tax           float64
-	UI            *UI
+	// RNG hands out the random streams for each subsystem
+	RNG *RNG
+	Tax float64
+	// TimeScale sets the speed of the simulation. The UI and rendering carry on regardless
+	TimeScale TimeScale
+	UI        *UI
+	Weather   *Weather
+
+	// accumulator holds frame time that hasn't been simulated yet
+	accumulator time.Duration
+	// alpha is how far between the last two updates we're rendering, from 0 to 1
+	alpha float32
+	// byID and index look entities up by their ID and category, and grids find them by where they are on the street
+	byID  map[EntityID]Entity
+	grids map[Category]*SpatialGrid
+	index map[Category][]Entity
+	// lastID is the last ID handed out
+	lastID EntityID
+	// spawns and despawns are queued up during a tick, and applied once it's finished
+	spawns, despawns []Entity
+	// resume is the time scale to go back to when unpausing
+	resume TimeScale
+	// step requests a single update while paused
+	step bool
 }
 
-// Draw renders any all entities stored in the engine
-func (e *Engine) Draw() {
-	for _, e := range e.Entities {
-		e.Draw()
+// drawOrder is the order categories are drawn in, from the back to the front
+var drawOrder = []Category{CategoryBuilding, CategoryVehicle, CategoryPerson, CategoryEffect}
+
+// Render queues the draws of every entity inside the view
+func (e *Engine) Render(queue *RenderQueue, view rl.Rectangle) {
+	for _, entity := range e.Visible(view) {
+		queue.PushEntity(entity)
 	}
 }
 
-// Update updates all entities stored in the engine
-func (e *Engine) Update() {
-	houses := 0.0
-	population := 0
-	e.BuildingBoxes = []rl.Rectangle{}
-	for i, entity := range e.Entities {
-		if e.UI.Halt == false {
-			entity.Update()
-		} else {
-			if reflect.TypeOf(entity) != reflect.TypeOf(&Person{}) && reflect.TypeOf(entity) != reflect.TypeOf(&Taxi{}) {
-				entity.Update()
-			}
-		}
-		// TODO - Refactor this. We don't need to run it every update most likely
-		typeOfEntity := reflect.TypeOf(entity)
-		if typeOfEntity == reflect.TypeOf(&Building{}) {
-			e.BuildingBoxes = append(e.BuildingBoxes, entity.GetHitbox())
-			houses++
-		}
-		if typeOfEntity == reflect.TypeOf(&Person{}) {
-			population++
+// Visible returns the entities inside the view, grouped by category in draw order, and within a category in the order
+// they were added
+func (e *Engine) Visible(view rl.Rectangle) []Entity {
+	visible := []Entity{}
+	for _, category := range drawOrder {
+		from := len(visible)
+		visible = e.grid(category).Query(view, visible)
+		inCategory := visible[from:]
+		sort.Slice(inCategory, func(i, j int) bool {
+			return inCategory[i].ID() < inCategory[j].ID()
+		})
+	}
+	return visible
+}
+
+// Advance adds the time elapsed since the last frame, scaled by the time scale, and runs an update for every full tick
+// that's built up. Whatever time is left over is used to interpolate rendering between the last two ticks.
+// Nothing runs while a dialog is halting the game, or while paused, unless a single step has been requested
+func (e *Engine) Advance(elapsed time.Duration) {
+	if e.UI != nil && e.UI.Halt {
+		return
+	}
+
+	if e.step {
+		e.step = false
+		e.accumulator = 0
+		e.tick()
+		e.alpha = 1
+		return
+	}
+
+	if elapsed > maxFrameTime {
+		elapsed = maxFrameTime
+	}
+	e.accumulator += elapsed * time.Duration(e.TimeScale)
+	for e.accumulator >= TickDuration {
+		e.tick()
+		e.accumulator -= TickDuration
+	}
+	e.alpha = float32(e.accumulator) / float32(TickDuration)
+}
+
+// tick snapshots where everything was, then runs an update
+func (e *Engine) tick() {
+	for _, entity := range e.Entities {
+		if s, ok := entity.(Snapshotter); ok {
+			s.Snapshot()
 		}
+	}
+	e.Update()
+}
+
+// SetTimeScale changes the speed of the simulation
+func (e *Engine) SetTimeScale(scale TimeScale) {
+	e.TimeScale = scale
+}
+
+// TogglePause pauses the simulation, or resumes it at whatever speed it was running before it was paused
+func (e *Engine) TogglePause() {
+	if e.TimeScale != Paused {
+		e.resume = e.TimeScale
+		e.TimeScale = Paused
+		return
+	}
+	e.TimeScale = e.resume
+	if e.TimeScale == Paused {
+		e.TimeScale = Normal
+	}
+}
+
+// Step pauses the simulation and runs a single update on the next Advance, for debugging frame by frame
+func (e *Engine) Step() {
+	if e.TimeScale != Paused {
+		e.TogglePause()
+	}
+	e.step = true
+}
+
+// Update runs a single update of the simulation, updating all entities stored in the engine
+// Anything spawned or despawned along the way waits until the end of the update, so every entity that was there at
+// the start gets updated exactly once
+func (e *Engine) Update() {
+	for _, entity := range e.Entities {
+		entity.Update()
+		e.grid(entity.Category()).Move(entity)
 		if entity.CanReap() {
-			if len(e.Entities) > i {
-				e.Entities = append(e.Entities[0:i], e.Entities[i+1:]...)
-			} else {
-				e.Entities = e.Entities[0:i]
-			}
+			e.Despawn(entity)
 		}
 	}
-	e.Population = population
+	for _, effect := range e.Effects {
+		effect(e)
+	}
+	e.flush()
+
+	houses := float64(len(e.index[CategoryBuilding]))
+	e.Population = len(e.index[CategoryPerson])
 
 	// Here's the economy part
 	// TODO - break this out into a package and design some tests to make the economy more iterable, and long term fun
 	if e.Dosh == 0 {
 		e.Dosh += 0.01
 	}
-	e.Dosh += float64(e.Population)*e.Tax*(0.0001*houses) + 0.0001
+	e.Calendar.Tick()
+	season := SeasonModifiers[e.Calendar.Season()]
+	e.Dosh += (float64(e.Population)*e.Tax*(0.0001*houses) + 0.0001) * season.Income
+	e.Dosh -= season.Heating * houses
+	if e.Dosh < 0 {
+		e.Dosh = 0
+	}
+	if e.Weather != nil {
+		e.Weather.Weights = season.Weather
+	}
 
-	// LightCycle Effects
-	// A good timespan is around 2000 cycles. Cycles 0-200 Should be sun up - 800-1000 sun down - and times between at the peaks of the Pi
-	// TODO - These constraints are poorly designed and results in an improper lightcycle. Resolve buggy lightcycle effects
-	e.Counter++
-	if e.Counter > 2000 {
-		e.Counter = 0
+	// The light cycle follows the sky, dimmed by any bad weather
+	e.Lightcycle = SkyColor(e.Calendar.TimeOfDay())
+	if e.Weather != nil {
+		e.Lightcycle = e.Weather.Light(e.Lightcycle)
 	}
-	if e.Counter <= 200 || (e.Counter >= 1000 && e.Counter <= 1200) {
-		e.Pi += math.Pi / 400
+}
+
+// Alpha returns how far between the last two updates the engine is rendering, from 0 to 1, for sprites to draw
+// themselves part of the way between the two. Without an engine, sprites are drawn where they are
+func (e *Engine) Alpha() float32 {
+	if e == nil {
+		return 1
 	}
-	if e.Pi > math.Pi {
-		e.Pi = 0
+	return e.alpha
+}
+
+// Rand returns the named random stream from the engine's RNG. Without an engine, streams come from a default seed
+func (e *Engine) Rand(stream string) *rand.Rand {
+	if e == nil {
+		return defaultRNG.Stream(stream)
 	}
+	if e.RNG == nil {
+		e.RNG = NewRNG(0)
+	}
+	return e.RNG.Stream(stream)
+}
 
-	R := uint8(49 + (191 * math.Sin(e.Pi)))
-	G := uint8(52 + (187 * math.Sin(e.Pi)))
-	B := uint8(49 + (191 * math.Sin(e.Pi)))
-	A := uint8(200 + (55 * math.Sin(e.Pi)))
+// Add gives the entity an ID and adds it to the engine straight away. Don't call it during a tick, use Spawn instead
+func (e *Engine) Add(entity Entity) EntityID {
+	id := e.register(entity)
+	e.insert(entity)
+	return id
+}
+
+// Spawn gives the entity an ID and queues it up to be added at the end of the tick, so it's safe to call from an
+// entity's Update or an engine effect. The entity can't be looked up until then
+func (e *Engine) Spawn(entity Entity) EntityID {
+	id := e.register(entity)
+	e.spawns = append(e.spawns, entity)
+	return id
+}
 
-	e.Lightcycle = rl.Color{R: R, G: G, B: B, A: A}
-	// fmt.Printf("Counter: %v\t Alpha: %v\tSinPi: %v\tPi: %v\n", e.Counter, (255 * math.Sin(e.Pi)), math.Sin(e.Pi), e.Pi)
+// Despawn queues the entity up to be removed at the end of the tick
+func (e *Engine) Despawn(entity Entity) {
+	e.despawns = append(e.despawns, entity)
+}
+
+// flush adds everything that was spawned during the tick, then removes everything that was despawned
+func (e *Engine) flush() {
+	for i, entity := range e.spawns {
+		e.insert(entity)
+		e.spawns[i] = nil
+	}
+	e.spawns = e.spawns[:0]
+	for i, entity := range e.despawns {
+		e.Remove(entity)
+		e.despawns[i] = nil
+	}
+	e.despawns = e.despawns[:0]
+}
+
+// register hands the entity the next ID
+func (e *Engine) register(entity Entity) EntityID {
+	e.lastID++
+	entity.setID(e.lastID)
+	return e.lastID
+}
+
+// insert adds the entity to the engine and its indexes
+func (e *Engine) insert(entity Entity) {
+	if e.byID == nil {
+		e.byID = make(map[EntityID]Entity)
+		e.index = make(map[Category][]Entity)
+	}
+	e.byID[entity.ID()] = entity
+
+	category := entity.Category()
+	e.index[category] = append(e.index[category], entity)
+	e.grid(category).Insert(entity)
+	e.Entities = append(e.Entities, entity)
+}
+
+// Remove takes the entity out of the engine and its indexes
+func (e *Engine) Remove(entity Entity) {
+	if _, ok := e.byID[entity.ID()]; !ok {
+		return
+	}
+	delete(e.byID, entity.ID())
+	category := entity.Category()
+	e.index[category] = without(e.index[category], entity)
+	e.grid(category).Remove(entity)
+	e.Entities = without(e.Entities, entity)
+	if r, ok := entity.(Releaser); ok {
+		r.Release()
+	}
+}
+
+// Moved refiles an entity whose hitbox changed outside of an update, like a building whose stamp was swapped
+func (e *Engine) Moved(entity Entity) {
+	e.grid(entity.Category()).Move(entity)
+}
+
+// grid returns the spatial grid for the category, creating it the first time it's needed
+func (e *Engine) grid(category Category) *SpatialGrid {
+	if e.grids == nil {
+		e.grids = make(map[Category]*SpatialGrid)
+	}
+	grid, ok := e.grids[category]
+	if !ok {
+		grid = NewSpatialGrid(gridCellWidth)
+		e.grids[category] = grid
+	}
+	return grid
+}
+
+// without removes the entity from the list in place, keeping the order of the rest
+func without(entities []Entity, entity Entity) []Entity {
+	for i, other := range entities {
+		if other == entity {
+			copy(entities[i:], entities[i+1:])
+			entities[len(entities)-1] = nil
+			return entities[:len(entities)-1]
+		}
+	}
+	return entities
+}
+
+// Get returns the entity with the given ID, or nil if it's not in the engine
+func (e *Engine) Get(id EntityID) Entity {
+	return e.byID[id]
+}
+
+// ByCategory returns every entity in the category. The slice belongs to the engine, so don't modify it
+func (e *Engine) ByCategory(category Category) []Entity {
+	return e.index[category]
+}
+
+// Intersecting returns every entity in the category whose hitbox overlaps the area
+func (e *Engine) Intersecting(category Category, area rl.Rectangle) []Entity {
+	return e.grid(category).Query(area, []Entity{})
+}
+
+// Pick returns the front most entity in the category under the point, or nil if there isn't one
+func (e *Engine) Pick(category Category, x, y float32) Entity {
+	var picked Entity
+	for _, entity := range e.grid(category).Query(rl.NewRectangle(x, y, 1, 1), nil) {
+		if !rl.CheckCollisionPointRec(rl.NewVector2(x, y), entity.GetHitbox()) {
+			continue
+		}
+		if picked == nil || entity.ID() > picked.ID() {
+			picked = entity
+		}
+	}
+	return picked
+}
+
+// Buildings returns all of the buildings currently standing in the city
+func (e *Engine) Buildings() []*Building {
+	buildings := []*Building{}
+	for _, entity := range e.index[CategoryBuilding] {
+		if building := entity.(*Building); !building.Deleted {
+			buildings = append(buildings, building)
+		}
+	}
+	return buildings
+}
+
+// BuildingsIn returns the buildings standing in the area
+func (e *Engine) BuildingsIn(area rl.Rectangle) []*Building {
+	buildings := []*Building{}
+	for _, entity := range e.Intersecting(CategoryBuilding, area) {
+		if building := entity.(*Building); !building.Deleted {
+			buildings = append(buildings, building)
+		}
+	}
+	return buildings
+}
+
+// People returns everyone living in the city
+func (e *Engine) People() []*Person {
+	people := []*Person{}
+	for _, entity := range e.index[CategoryPerson] {
+		people = append(people, entity.(*Person))
+	}
+	return people
 }
 
-// IsCollidedWithType takes a target entity and tells you if it's collided with another entity of the given type
-func (e *Engine) IsCollidedWithType(target Entity, targetType reflect.Type) bool {
-	for _, e := range e.Entities {
-		if reflect.TypeOf(e) == targetType {
-			if rl.CheckCollisionRecs(target.GetHitbox(), e.GetHitbox()) {
-				return true
-			}
+// IsCollidedWith takes a target entity and tells you if it's collided with another entity in the category
+func (e *Engine) IsCollidedWith(target Entity, category Category) bool {
+	for _, entity := range e.Intersecting(category, target.GetHitbox()) {
+		if entity != target {
+			return true
 		}
 	}
 	return false
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeScale(t *testing.T) {
+	engine := &Engine{TimeScale: Normal}
+	engine.Advance(TickDuration)
+	assert.Equal(t, 1, engine.Calendar.Ticks)
+
+	engine.SetTimeScale(Fastest)
+	engine.Advance(TickDuration)
+	assert.Equal(t, 5, engine.Calendar.Ticks)
+
+	engine.TogglePause()
+	engine.Advance(TickDuration)
+	assert.Equal(t, 5, engine.Calendar.Ticks)
+
+	engine.Step()
+	engine.Advance(TickDuration)
+	engine.Advance(TickDuration)
+	assert.Equal(t, 6, engine.Calendar.Ticks)
+
+	// Resuming picks back up at the speed we paused at
+	engine.TogglePause()
+	assert.Equal(t, Fastest, engine.TimeScale)
+}
+
+func TestStepPauses(t *testing.T) {
+	engine := &Engine{TimeScale: Fast}
+	engine.Step()
+	assert.Equal(t, Paused, engine.TimeScale)
+	engine.Advance(TickDuration)
+	assert.Equal(t, 1, engine.Calendar.Ticks)
+}
+
+func TestHaltStopsSimulation(t *testing.T) {
+	engine := &Engine{TimeScale: Normal, UI: &UI{Halt: true}}
+	engine.Advance(TickDuration)
+	assert.Equal(t, 0, engine.Calendar.Ticks)
+}
+
+func TestFixedTimestep(t *testing.T) {
+	// A second of frames should run a second of updates, no matter the frame rate
+	for _, fps := range []int{30, 60, 144} {
+		engine := &Engine{TimeScale: Normal}
+		for i := 0; i < fps; i++ {
+			engine.Advance(time.Second / time.Duration(fps))
+		}
+		assert.InDelta(t, TickRate, engine.Calendar.Ticks, 1, "at %v FPS", fps)
+	}
+
+	// Leftover time carries over into the next frame
+	engine := &Engine{TimeScale: Normal}
+	other := &Engine{TimeScale: Normal}
+	other.Advance(TickDuration / 4)
+	engine.Advance(TickDuration / 2)
+	assert.Equal(t, 0, engine.Calendar.Ticks)
+	assert.Equal(t, float32(0.5), engine.Alpha())
+	// Each engine renders its own way between updates
+	assert.InDelta(t, 0.25, other.Alpha(), 0.001)
+	engine.Advance(TickDuration / 2)
+	assert.Equal(t, 1, engine.Calendar.Ticks)
+
+	// A long stall doesn't try to catch up all at once
+	engine.Advance(time.Minute)
+	assert.Equal(t, 1+int(maxFrameTime/TickDuration), engine.Calendar.Ticks)
+}
+
+func TestSpriteInterpolation(t *testing.T) {
+	sprite := &Sprite{LevelX: 10}
+	assert.Equal(t, float32(10), sprite.Position(0.5).X)
+
+	sprite.Snapshot()
+	sprite.LevelX = 14
+	assert.Equal(t, float32(12), sprite.Position(0.5).X)
+	// Without an engine it's drawn where it is
+	assert.Equal(t, float32(14), sprite.Position((*Engine)(nil).Alpha()).X)
+
+	// Teleports aren't smeared across the screen
+	sprite.LevelX = 1000
+	assert.Equal(t, float32(1000), sprite.Position(0.5).X)
+}
+
+func TestEntityRegistry(t *testing.T) {
+	engine := &Engine{}
+	taxi := &Taxi{}
+	person := &Person{}
+	engine.Add(taxi)
+	engine.Add(person)
+	house := PlaceBuilding(engine, GetHouse(engine, nil), nil, 100)
+	station := PlaceBuilding(engine, GetFireStation(engine, nil), nil, 300)
+
+	assert.NotEqual(t, house.ID(), station.ID())
+	assert.Equal(t, house, engine.Get(house.ID()))
+	assert.Equal(t, []*Person{person}, engine.People())
+	assert.Len(t, engine.ByCategory(CategoryVehicle), 1)
+	// Entities stay in the order they were added. The render queue decides what's drawn in front
+	assert.Equal(t, Entity(station), engine.Entities[3])
+
+	assert.Equal(t, []*Building{house}, engine.BuildingsIn(rl.NewRectangle(90, 0, 20, float32(GroundLevel)+32)))
+
+	preview := GetHouse(engine, nil)
+	preview.Stamp.LevelX, preview.Stamp.LevelY = house.Stamp.LevelX+8, house.Stamp.LevelY
+	assert.True(t, engine.IsCollidedWith(preview, CategoryBuilding))
+
+	engine.Remove(house)
+	assert.Nil(t, engine.Get(house.ID()))
+	assert.Equal(t, []*Building{station}, engine.Buildings())
+	assert.Len(t, engine.Entities, 3)
+}
+
+// counter is a bare entity that counts its updates, and can reap itself or spawn more counters
+type counter struct {
+	Identity
+	engine  *Engine
+	reap    bool
+	spawns  int
+	updates int
+}
+
+func (c *counter) CanReap() bool           { return c.reap }
+func (c *counter) Category() Category      { return CategoryEffect }
+func (c *counter) Draw()                   {}
+func (c *counter) GetHitbox() rl.Rectangle { return rl.Rectangle{} }
+func (c *counter) Update() {
+	c.updates++
+	for i := 0; i < c.spawns; i++ {
+		c.engine.Spawn(&counter{engine: c.engine})
+	}
+}
+
+func TestReapingSkipsNothing(t *testing.T) {
+	engine := &Engine{}
+	counters := []*counter{}
+	for i := 0; i < 100; i++ {
+		c := &counter{engine: engine, reap: i%2 == 0}
+		counters = append(counters, c)
+		engine.Add(c)
+	}
+
+	engine.Update()
+	for _, c := range counters {
+		assert.Equal(t, 1, c.updates)
+	}
+	assert.Len(t, engine.Entities, 50)
+	for _, entity := range engine.Entities {
+		assert.False(t, entity.CanReap())
+	}
+}
+
+func TestSpawnWaitsForTheNextTick(t *testing.T) {
+	engine := &Engine{}
+	parent := &counter{engine: engine, spawns: 10}
+	engine.Add(parent)
+
+	engine.Update()
+	assert.Len(t, engine.Entities, 11)
+	for _, entity := range engine.Entities[1:] {
+		assert.Equal(t, 0, entity.(*counter).updates)
+		assert.Equal(t, entity, engine.Get(entity.ID()))
+	}
+
+	parent.spawns = 0
+	engine.Update()
+	assert.Equal(t, 2, parent.updates)
+	for _, entity := range engine.Entities[1:] {
+		assert.Equal(t, 1, entity.(*counter).updates)
+	}
+}
+
+func TestSpawnAndDespawnInOneTick(t *testing.T) {
+	engine := &Engine{}
+	doomed := &counter{engine: engine}
+	engine.Effects = append(engine.Effects, func(e *Engine) {
+		e.Spawn(doomed)
+		e.Despawn(doomed)
+	})
+	engine.Update()
+	assert.Empty(t, engine.Entities)
+	assert.Nil(t, engine.Get(doomed.ID()))
+}
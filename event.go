@@ -11,25 +11,33 @@ type Event struct {
 	Done      bool
 	Execute   func()
 	Sound     rl.Sound
+	SoundPath string
 	Trigger   func() bool
 	Triggered bool
 }
 
+// Release gives the event's sound back to the asset manager
+func (event *Event) Release() {
+	Assets.ReleaseSound(event.SoundPath)
+}
+
 // PlaySound plays the event indicator
 func (event *Event) PlaySound() {
 	rl.PlaySound(event.Sound)
 }
 
-// NewEventDuration creates a Duration based event. Pass your function and the duration you want to spawn it in
+// NewEventDuration creates a Duration based event. Pass your function and the duration you want to spawn it in.
+// Time is measured by the input clock, so replays trigger events on the same frame
 func NewEventDuration(triggerIn time.Duration, execute func()) *Event {
-	endsAt := time.Now().Add(triggerIn)
+	endsAt := Input.Now() + triggerIn
 	trigger := func() bool {
-		return time.Now().After(endsAt)
+		return Input.Now() > endsAt
 	}
 
 	return &Event{
-		Execute: execute,
-		Sound:   rl.LoadSound("assets/sounds/ui1.mp3"),
-		Trigger: trigger,
+		Execute:   execute,
+		Sound:     Assets.Sound("assets/sounds/ui1.mp3"),
+		SoundPath: "assets/sounds/ui1.mp3",
+		Trigger:   trigger,
 	}
-}
\ No newline at end of file
+}
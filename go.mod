@@ -1,6 +1,6 @@
 module github.com/goshlang/pixelopolis
 
-go 1.14
+go 1.16
 
 require (
 	github.com/gen2brain/raylib-go v0.0.0-20200905153824-5b0944b5567a
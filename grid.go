@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// gridCellWidth is how wide a column of the spatial grid is. About the width of a house, so most entities sit in one or two
+const gridCellWidth = 64
+
+// span is the range of grid columns an entity was filed under
+type span struct {
+	first, last int
+}
+
+// SpatialGrid files entities into columns along the street by their hitboxes, so finding what's in an area only looks at
+// the columns it covers instead of every entity in the city
+type SpatialGrid struct {
+	CellWidth float32
+	cells     map[int][]Entity
+	spans     map[Entity]span
+}
+
+// NewSpatialGrid returns an empty grid with columns of the given width
+func NewSpatialGrid(cellWidth float32) *SpatialGrid {
+	return &SpatialGrid{
+		CellWidth: cellWidth,
+		cells:     make(map[int][]Entity),
+		spans:     make(map[Entity]span),
+	}
+}
+
+// Len returns how many entities are in the grid
+func (g *SpatialGrid) Len() int {
+	return len(g.spans)
+}
+
+// Insert files the entity under every column its hitbox covers
+func (g *SpatialGrid) Insert(entity Entity) {
+	s := g.spanOf(entity.GetHitbox())
+	g.spans[entity] = s
+	for cell := s.first; cell <= s.last; cell++ {
+		g.cells[cell] = append(g.cells[cell], entity)
+	}
+}
+
+// Remove takes the entity out of the grid
+func (g *SpatialGrid) Remove(entity Entity) {
+	s, ok := g.spans[entity]
+	if !ok {
+		return
+	}
+	delete(g.spans, entity)
+	for cell := s.first; cell <= s.last; cell++ {
+		g.cells[cell] = without(g.cells[cell], entity)
+		if len(g.cells[cell]) == 0 {
+			delete(g.cells, cell)
+		}
+	}
+}
+
+// Move refiles the entity if its hitbox has moved into different columns. Most updates don't, so this is usually free
+func (g *SpatialGrid) Move(entity Entity) {
+	s, ok := g.spans[entity]
+	if !ok || s == g.spanOf(entity.GetHitbox()) {
+		return
+	}
+	g.Remove(entity)
+	g.Insert(entity)
+}
+
+// Query appends every entity whose hitbox overlaps the area to found, and returns it. Entities come back in column order
+func (g *SpatialGrid) Query(area rl.Rectangle, found []Entity) []Entity {
+	s := g.spanOf(area)
+	for cell := s.first; cell <= s.last; cell++ {
+		for _, entity := range g.cells[cell] {
+			// An entity covering several columns is only reported from the first column it shares with the query
+			first := g.spans[entity].first
+			if first < s.first {
+				first = s.first
+			}
+			if cell != first {
+				continue
+			}
+			if rl.CheckCollisionRecs(area, entity.GetHitbox()) {
+				found = append(found, entity)
+			}
+		}
+	}
+	return found
+}
+
+// spanOf returns the columns the rectangle covers
+func (g *SpatialGrid) spanOf(area rl.Rectangle) span {
+	return span{
+		first: int(math.Floor(float64(area.X / g.CellWidth))),
+		last:  int(math.Floor(float64((area.X + area.Width) / g.CellWidth))),
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+// box is a bare entity with a hitbox, for testing the grid
+type box struct {
+	Identity
+	category Category
+	hitbox   rl.Rectangle
+}
+
+func (b *box) CanReap() bool           { return false }
+func (b *box) Category() Category      { return b.category }
+func (b *box) Draw()                   {}
+func (b *box) GetHitbox() rl.Rectangle { return b.hitbox }
+func (b *box) Update()                 {}
+
+func TestGridQuery(t *testing.T) {
+	grid := NewSpatialGrid(gridCellWidth)
+	small := &box{hitbox: rl.NewRectangle(10, 0, 16, 16)}
+	wide := &box{hitbox: rl.NewRectangle(-100, 0, 400, 16)}
+	far := &box{hitbox: rl.NewRectangle(1000, 0, 16, 16)}
+	grid.Insert(small)
+	grid.Insert(wide)
+	grid.Insert(far)
+
+	// The wide box covers several columns, but only comes back once
+	assert.ElementsMatch(t, []Entity{small, wide}, grid.Query(rl.NewRectangle(0, 0, 200, 16), nil))
+	assert.Equal(t, []Entity{wide}, grid.Query(rl.NewRectangle(150, 0, 300, 16), nil))
+	assert.Empty(t, grid.Query(rl.NewRectangle(500, 0, 100, 16), nil))
+
+	far.hitbox.X = 520
+	grid.Move(far)
+	assert.Equal(t, []Entity{far}, grid.Query(rl.NewRectangle(500, 0, 100, 16), nil))
+
+	grid.Remove(wide)
+	assert.Equal(t, 2, grid.Len())
+	assert.Empty(t, grid.Query(rl.NewRectangle(150, 0, 300, 16), nil))
+}
+
+func TestPick(t *testing.T) {
+	engine := &Engine{}
+	back := &box{category: CategoryPerson, hitbox: rl.NewRectangle(0, 0, 32, 32)}
+	front := &box{category: CategoryPerson, hitbox: rl.NewRectangle(16, 0, 32, 32)}
+	engine.Add(back)
+	engine.Add(front)
+
+	assert.Equal(t, Entity(back), engine.Pick(CategoryPerson, 8, 8))
+	assert.Equal(t, Entity(front), engine.Pick(CategoryPerson, 24, 8))
+	assert.Nil(t, engine.Pick(CategoryPerson, 100, 8))
+}
+
+// city lays out count entities along the street, some buildings and the rest people, the way a big city would
+func city(count int) *Engine {
+	engine := &Engine{}
+	for i := 0; i < count; i++ {
+		category := CategoryPerson
+		if i%4 == 0 {
+			category = CategoryBuilding
+		}
+		engine.Add(&box{category: category, hitbox: rl.NewRectangle(float32(i*8), 0, 48, 32)})
+	}
+	return engine
+}
+
+func BenchmarkLinearCollision10k(b *testing.B) {
+	engine := city(10000)
+	target := &box{hitbox: rl.NewRectangle(40000, 0, 48, 32)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, entity := range engine.ByCategory(CategoryBuilding) {
+			if rl.CheckCollisionRecs(target.GetHitbox(), entity.GetHitbox()) {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkGridCollision10k(b *testing.B) {
+	engine := city(10000)
+	target := &box{hitbox: rl.NewRectangle(40000, 0, 48, 32)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.IsCollidedWith(target, CategoryBuilding)
+	}
+}
+
+func BenchmarkGridMove10k(b *testing.B) {
+	engine := city(10000)
+	people := engine.ByCategory(CategoryPerson)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, person := range people {
+			person.(*box).hitbox.X++
+			engine.grid(CategoryPerson).Move(person)
+		}
+	}
+}
+
+func BenchmarkVisible10k(b *testing.B) {
+	engine := city(10000)
+	view := rl.NewRectangle(20000, 0, 1920, 1080)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.Visible(view)
+	}
+}
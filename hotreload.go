@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Watcher polls a directory for files that have changed since it last looked
+type Watcher struct {
+	Dir string
+	// Interval is how often Poll actually looks at the disk
+	Interval time.Duration
+
+	checked time.Time
+	mtimes  map[string]time.Time
+}
+
+// NewWatcher returns a watcher for the directory, remembering what's in it now so only later changes are reported
+func NewWatcher(dir string, interval time.Duration) *Watcher {
+	w := &Watcher{Dir: dir, Interval: interval, mtimes: make(map[string]time.Time)}
+	w.scan()
+	w.checked = time.Now()
+	return w
+}
+
+// Poll returns the files that have been added or changed, as slash separated paths relative to the directory.
+// It's cheap to call every frame, since it only looks at the disk once per interval
+func (w *Watcher) Poll() []string {
+	if time.Since(w.checked) < w.Interval {
+		return nil
+	}
+	w.checked = time.Now()
+	return w.scan()
+}
+
+// scan walks the directory, recording each file's modified time, and returns the ones that changed
+func (w *Watcher) scan() []string {
+	changed := []string{}
+	filepath.Walk(w.Dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(w.Dir, file)
+		if err != nil {
+			return nil
+		}
+		name = filepath.ToSlash(name)
+		if last, ok := w.mtimes[name]; !ok || !last.Equal(info.ModTime()) {
+			changed = append(changed, name)
+		}
+		w.mtimes[name] = info.ModTime()
+		return nil
+	})
+	return changed
+}
+
+// HotReload watches the asset override directory during development, and swaps changed Tiled files and spritesheets
+// into the running city without restarting it
+type HotReload struct {
+	Engine  *Engine
+	Watcher *Watcher
+}
+
+// NewHotReload watches the assets under the override directory
+func NewHotReload(engine *Engine, dir string) *HotReload {
+	return &HotReload{Engine: engine, Watcher: NewWatcher(filepath.Join(dir, "assets"), time.Second/2)}
+}
+
+// Update reloads anything that's changed since the last poll
+func (h *HotReload) Update() {
+	for _, name := range h.Watcher.Poll() {
+		h.Reload(path.Join("assets", name))
+	}
+}
+
+// Reload reparses the asset and swaps it into everything using it. Tiled files replace the stamps of the buildings
+// built from them, animations exported next to a spritesheet replace their clips' frames, and spritesheets are redrawn
+// in place
+func (h *HotReload) Reload(name string) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		if _, ok := animationCache[name]; ok {
+			h.reloadAnimations(name)
+			return
+		}
+		h.reloadStamp(name)
+	case ".png":
+		h.reloadSpritesheet(name)
+	}
+}
+
+// reloadStamp swaps the stamp of every building made from the Tiled file, keeping where it stands
+func (h *HotReload) reloadStamp(name string) {
+	stamp, err := GetStampFromTiledFile(name)
+	if err != nil {
+		fmt.Printf("Couldn't reload %v: %v\n", name, err)
+		return
+	}
+
+	buildings := h.Engine.Buildings()
+	if h.Engine.UI != nil && h.Engine.UI.BuildingCache != nil {
+		buildings = append(buildings, h.Engine.UI.BuildingCache)
+	}
+	for _, building := range buildings {
+		if building.Filepath != name {
+			continue
+		}
+		building.Stamp.DrawCoords = stamp.DrawCoords
+		building.Stamp.Width = stamp.Width
+		building.Stamp.Height = stamp.Height
+		building.Stamp.LevelY = float32(GroundLevel) - building.Stamp.Height + 16
+		h.Engine.Moved(building)
+	}
+	fmt.Printf("Reloaded %v\n", name)
+}
+
+// reloadAnimations swaps the frames of every clip in the export
+func (h *HotReload) reloadAnimations(name string) {
+	if err := ReloadAnimations(name); err != nil {
+		fmt.Printf("Couldn't reload %v: %v\n", name, err)
+		return
+	}
+	fmt.Printf("Reloaded %v\n", name)
+}
+
+// reloadSpritesheet redraws the spritesheet's texture, and reslices any palette cut from it or recolored from it
+func (h *HotReload) reloadSpritesheet(name string) {
+	texture, reloaded := Assets.ReloadTexture(name)
+	if h.Engine.UI != nil {
+		for _, palette := range h.Engine.UI.Palettes {
+			if palette.Spritesheet == name && reloaded {
+				reslice(palette, texture)
+			}
+			for _, variant := range palette.Variants {
+				if variant.Base != name {
+					continue
+				}
+				if texture, ok := Assets.ReloadTexture(variant.Spritesheet); ok {
+					reslice(variant, texture)
+					reloaded = true
+				}
+			}
+		}
+	}
+	if reloaded {
+		fmt.Printf("Reloaded %v\n", name)
+	}
+}
+
+// reslice swaps the palette's texture, and cuts it back up into brushes
+func reslice(palette *Palette, texture rl.Texture2D) {
+	palette.Generation++
+	palette.Texture = texture
+	palette.Width, palette.Height = int(texture.Width), int(texture.Height)
+	palette.Brushes = BrushMap(palette.Width, palette.Height, palette.TileWidth, palette.TileHeight, palette.Margin, palette.Spacing)
+}
+
+// decodeColors decodes an image into raylib's pixel format
+func decodeColors(data []byte) (int, int, []rl.Color, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	bounds := decoded.Bounds()
+	rgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), decoded, bounds.Min, draw.Src)
+
+	pixels := make([]rl.Color, 0, bounds.Dx()*bounds.Dy())
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		pixels = append(pixels, rl.NewColor(rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2], rgba.Pix[i+3]))
+	}
+	return bounds.Dx(), bounds.Dy(), pixels, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pixelopolis")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "buildings", "slum.json")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	assert.NoError(t, ioutil.WriteFile(file, []byte("{}"), 0644))
+
+	watcher := NewWatcher(dir, 0)
+	assert.Empty(t, watcher.Poll())
+
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(file, later, later))
+	assert.Equal(t, []string{"buildings/slum.json"}, watcher.Poll())
+	assert.Empty(t, watcher.Poll())
+}
+
+func TestReloadStamp(t *testing.T) {
+	slum, err := ReadAsset("assets/buildings/slum/2.json")
+	assert.NoError(t, err)
+	church, err := ReadAsset("assets/buildings/slum/church.json")
+	assert.NoError(t, err)
+
+	modded := fstest.MapFS{"assets/buildings/slum/2.json": {Data: slum}}
+	defer func(bundled fs.FS) { AssetFS = bundled }(AssetFS)
+	AssetFS = LayeredFS{modded, embeddedAssets}
+
+	engine := &Engine{}
+	building := PlaceBuilding(engine, GetSlum(engine, nil), nil, 200)
+	before := building.Stamp
+
+	// Swap the slum out for the church, as if it had been redrawn in Tiled
+	modded["assets/buildings/slum/2.json"] = &fstest.MapFile{Data: church}
+	reload := &HotReload{Engine: engine}
+	reload.Reload("assets/buildings/slum/2.json")
+
+	expected, err := GetStampFromTiledFile("assets/buildings/slum/church.json")
+	assert.NoError(t, err)
+	assert.Equal(t, expected.DrawCoords, building.Stamp.DrawCoords)
+	assert.Equal(t, expected.Height, building.Stamp.Height)
+	assert.Equal(t, before.LevelX, building.Stamp.LevelX)
+	assert.Equal(t, []*Building{building}, engine.BuildingsIn(building.GetHitbox()))
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// InputFrame is everything the player did during a single frame
+type InputFrame struct {
+	// Buttons are the UI buttons clicked this frame
+	Buttons []string `json:"buttons,omitempty"`
+	// Elapsed is how long the frame took, which decides how many ticks it runs
+	Elapsed time.Duration `json:"elapsed"`
+	// Held are the keys held down this frame, for anything that happens for as long as a key is held
+	Held []int32 `json:"held,omitempty"`
+	// Keys are the keys pressed this frame
+	Keys         []int32 `json:"keys,omitempty"`
+	MouseDown    []int32 `json:"mouseDown,omitempty"`
+	MousePressed []int32 `json:"mousePressed,omitempty"`
+	MouseX       int32   `json:"mouseX"`
+	MouseY       int32   `json:"mouseY"`
+	// Tick is how many ticks the city had run when the frame started, so a replay can tell if it's drifted
+	Tick int `json:"tick"`
+}
+
+// ReplayHeader is written at the top of a recording, with everything needed to start the same city back up
+type ReplayHeader struct {
+	// DayLength is how many updates made up a day. Recordings made before it could be changed leave it unset, for the
+	// default
+	DayLength int    `json:"dayLength,omitempty"`
+	Load      string `json:"load,omitempty"`
+	// Mods are the mods that were enabled, in load order
+	Mods    []string `json:"mods,omitempty"`
+	ScreenX int32    `json:"screenX"`
+	ScreenY int32    `json:"screenY"`
+	Seed    int64    `json:"seed"`
+}
+
+// InputState is where the game reads the player's input from. Live, it samples raylib once a frame. While replaying it
+// hands back the recorded frames instead, so the session plays out exactly as it was recorded
+type InputState struct {
+	// Clock is the total time played, built up from each frame's elapsed time
+	Clock    time.Duration
+	Frame    InputFrame
+	Recorder *Recorder
+	Replay   *Replay
+}
+
+// Input holds the player's input for the current frame
+var Input = &InputState{}
+
+// Begin starts a new frame of input, either sampled from raylib or read from the replay. It returns io.EOF once a
+// replay has run out of frames
+func (in *InputState) Begin(elapsed time.Duration, tick int) error {
+	if in.Replay != nil {
+		frame, err := in.Replay.Next()
+		if err != nil {
+			return err
+		}
+		if frame.Tick != tick {
+			return fmt.Errorf("replay drifted: frame expected tick %v, city is at tick %v", frame.Tick, tick)
+		}
+		in.Frame = frame
+	} else {
+		in.Frame = in.sample(elapsed, tick)
+	}
+	in.Clock += in.Frame.Elapsed
+	return nil
+}
+
+// End finishes the frame, writing it to the recording if we're recording
+func (in *InputState) End() error {
+	if in.Recorder == nil {
+		return nil
+	}
+	return in.Recorder.Write(in.Frame)
+}
+
+// sample reads the mouse and every bound key from raylib
+func (in *InputState) sample(elapsed time.Duration, tick int) InputFrame {
+	frame := InputFrame{
+		Elapsed: elapsed,
+		MouseX:  rl.GetMouseX(),
+		MouseY:  rl.GetMouseY(),
+		Tick:    tick,
+	}
+	for _, button := range []int32{rl.MouseLeftButton, rl.MouseRightButton} {
+		if rl.IsMouseButtonDown(button) {
+			frame.MouseDown = append(frame.MouseDown, button)
+		}
+		if rl.IsMouseButtonPressed(button) {
+			frame.MousePressed = append(frame.MousePressed, button)
+		}
+	}
+	for _, key := range Keybindings {
+		if rl.IsKeyPressed(key) && !contains(frame.Keys, key) {
+			frame.Keys = append(frame.Keys, key)
+		}
+		if rl.IsKeyDown(key) && !contains(frame.Held, key) {
+			frame.Held = append(frame.Held, key)
+		}
+	}
+	return frame
+}
+
+// Button takes whether a UI button was clicked, and records it. While replaying, the recorded click wins instead
+func (in *InputState) Button(name string, clicked bool) bool {
+	if in.Replay != nil {
+		for _, b := range in.Frame.Buttons {
+			if b == name {
+				return true
+			}
+		}
+		return false
+	}
+	if clicked {
+		in.Frame.Buttons = append(in.Frame.Buttons, name)
+	}
+	return clicked
+}
+
+// KeyPressed returns true if the key was pressed this frame
+func (in *InputState) KeyPressed(key int32) bool {
+	return contains(in.Frame.Keys, key)
+}
+
+// KeyDown returns true while the key is held
+func (in *InputState) KeyDown(key int32) bool {
+	return contains(in.Frame.Held, key)
+}
+
+// MouseDown returns true while the mouse button is held
+func (in *InputState) MouseDown(button int32) bool {
+	return contains(in.Frame.MouseDown, button)
+}
+
+// MousePressed returns true if the mouse button was pressed this frame
+func (in *InputState) MousePressed(button int32) bool {
+	return contains(in.Frame.MousePressed, button)
+}
+
+// MouseX returns the mouse's X position
+func (in *InputState) MouseX() int32 {
+	return in.Frame.MouseX
+}
+
+// MouseY returns the mouse's Y position
+func (in *InputState) MouseY() int32 {
+	return in.Frame.MouseY
+}
+
+// Now returns how long the game has been played for. Use it instead of the wall clock so replays keep their timing
+func (in *InputState) Now() time.Duration {
+	return in.Clock
+}
+
+// Recorder writes each frame of input to a file, one JSON line per frame after the header
+type Recorder struct {
+	file    *os.File
+	encoder *json.Encoder
+	writer  *bufio.Writer
+}
+
+// NewRecorder creates the recording file and writes its header
+func NewRecorder(filepath string, header ReplayHeader) (*Recorder, error) {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	recorder := &Recorder{file: file, encoder: json.NewEncoder(writer), writer: writer}
+	if err := recorder.encoder.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// Write appends a frame to the recording
+func (r *Recorder) Write(frame InputFrame) error {
+	return r.encoder.Encode(frame)
+}
+
+// Close flushes and closes the recording
+func (r *Recorder) Close() error {
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// Replay reads back a recording frame by frame
+type Replay struct {
+	Header  ReplayHeader
+	decoder *json.Decoder
+	file    *os.File
+}
+
+// OpenReplay opens a recording and reads its header
+func OpenReplay(filepath string) (*Replay, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	replay := &Replay{decoder: json.NewDecoder(bufio.NewReader(file)), file: file}
+	if err := replay.decoder.Decode(&replay.Header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Next returns the next recorded frame, or io.EOF when there are none left
+func (r *Replay) Next() (InputFrame, error) {
+	frame := InputFrame{}
+	err := r.decoder.Decode(&frame)
+	return frame, err
+}
+
+// Close closes the recording
+func (r *Replay) Close() error {
+	return r.file.Close()
+}
+
+// contains returns true if the value is in the list
+func contains(list []int32, value int32) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
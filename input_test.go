@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.replay")
+	header := ReplayHeader{DayLength: 600, ScreenX: 640, ScreenY: 480, Seed: 42}
+	frames := []InputFrame{
+		{Elapsed: TickDuration, MouseX: 10, MouseY: 20, Tick: 0},
+		{Buttons: []string{"house"}, Elapsed: TickDuration, Keys: []int32{rl.KeyP}, MouseDown: []int32{rl.MouseLeftButton}, Tick: 1},
+	}
+
+	recorder, err := NewRecorder(path, header)
+	assert.NoError(t, err)
+	for _, frame := range frames {
+		assert.NoError(t, recorder.Write(frame))
+	}
+	assert.NoError(t, recorder.Close())
+
+	replay, err := OpenReplay(path)
+	assert.NoError(t, err)
+	defer replay.Close()
+	assert.Equal(t, header, replay.Header)
+
+	input := &InputState{Replay: replay}
+	assert.NoError(t, input.Begin(0, 0))
+	assert.Equal(t, int32(10), input.MouseX())
+	assert.False(t, input.Button("house", false))
+
+	// Live input is ignored while replaying
+	assert.NoError(t, input.Begin(0, 1))
+	assert.True(t, input.Button("house", false))
+	assert.False(t, input.Button("slum", true))
+	assert.True(t, input.KeyPressed(rl.KeyP))
+	assert.True(t, input.MouseDown(rl.MouseLeftButton))
+	assert.Equal(t, 2*TickDuration, input.Now())
+
+	assert.Equal(t, io.EOF, input.Begin(0, 2))
+}
+
+func TestReplayDrift(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.replay")
+	recorder, err := NewRecorder(path, ReplayHeader{})
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Write(InputFrame{Elapsed: time.Second, Tick: 5}))
+	assert.NoError(t, recorder.Close())
+
+	replay, err := OpenReplay(path)
+	assert.NoError(t, err)
+	defer replay.Close()
+	input := &InputState{Replay: replay}
+	assert.Error(t, input.Begin(0, 4))
+}
@@ -5,7 +5,51 @@ import rl "github.com/gen2brain/raylib-go/raylib"
 // Entity allows custom objects to be grouped together through this interface
 type Entity interface {
 	CanReap() bool
+	// Category is which of the engine's indexes the entity is kept in
+	Category() Category
 	Draw()
 	GetHitbox() rl.Rectangle
+	// ID is the entity's stable ID, handed out by the engine when the entity is added
+	ID() EntityID
 	Update()
+
+	setID(EntityID)
+}
+
+// Releaser is an entity holding assets, which gives them back to the asset manager when it's removed from the engine
+type Releaser interface {
+	Release()
+}
+
+// Snapshotter is an entity that moves, and remembers where it was before each update so it can be drawn between updates
+type Snapshotter interface {
+	Snapshot()
+}
+
+// Category groups entities by what they are, so the engine can index them without inspecting their types
+type Category int
+
+const (
+	// CategoryEffect covers everything that isn't a building, person or vehicle, like weather and disasters
+	CategoryEffect Category = iota
+	CategoryBuilding
+	CategoryPerson
+	CategoryVehicle
+)
+
+// EntityID identifies an entity for as long as it's in the engine. 0 means it hasn't been added yet
+type EntityID uint64
+
+// Identity holds an entity's ID. Embed it in an entity to satisfy the ID part of the Entity interface
+type Identity struct {
+	id EntityID
+}
+
+// ID returns the entity's ID
+func (i *Identity) ID() EntityID {
+	return i.id
+}
+
+func (i *Identity) setID(id EntityID) {
+	i.id = id
 }
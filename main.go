@@ -1,8 +1,12 @@
 package main
 
 import (
-	"math/rand"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -16,31 +20,189 @@ var (
 	ScreenX     = int32(rl.GetMonitorWidth(0))
 	ScreenY     = int32(rl.GetMonitorHeight(0))
 	// calculate the rate of increase. If we want it to happen once a minute, you have to consider the rate
-	// that we're moving at is 60 FPS, so 60 * 60 = the rate of 1 minute.
-	rate = 60.0 * 60.0
+	// that the simulation ticks at is 60 times a second, so 60 * 60 = the rate of 1 minute.
+	rate = TickRate * 60.0
 )
 
+// Config holds the options passed in on the command line
+type Config struct {
+	// Assets is a directory laid out like the repo, whose assets are used over the bundled ones
+	Assets string
+	// DayLength is how many updates make up a day in the city. 0 leaves it to the loaded save, or the default
+	DayLength int
+	// Dev watches the assets and reloads them into the running city when they change. Without an assets directory it
+	// watches the one we're running from
+	Dev bool
+	// Hidden hides the window, and runs replays as fast as they'll go. The window still needs a display and OpenGL to
+	// draw into, so on a machine without one run it under a virtual display like xvfb-run
+	Hidden bool
+	// Load is a save file to load the city from
+	Load string
+	// Mods is the directory mods are discovered in
+	Mods string
+	// Record is a file to record the player's input to
+	Record string
+	// Replay is a recording to play back instead of taking the player's input
+	Replay string
+	// Save is where the city is written to when saving
+	Save string
+	// Screenshots is the directory screenshots and timelapses are saved in
+	Screenshots string
+	// Seed seeds all of the city's randomness. 0 picks a seed from the clock
+	Seed int64
+	// Snapshot is a file to save the last frame to when the game exits, for checking hidden runs by eye
+	Snapshot string
+	// Timelapse starts recording a timelapse with the city, rather than waiting for the hotkey
+	Timelapse bool
+	// TimelapseHours is how many in-game hours apart timelapse frames are captured
+	TimelapseHours float64
+}
+
+// ParseConfig parses the command line arguments into a Config
+func ParseConfig(args []string) (Config, error) {
+	config := Config{}
+	flags := flag.NewFlagSet("pixelopolis", flag.ContinueOnError)
+	flags.StringVar(&config.Assets, "assets", "", "use assets from this directory over the bundled ones, like assets/sprites/mega.png")
+	flags.IntVar(&config.DayLength, "day-length", 0, fmt.Sprintf("how many updates make up a day, so days pass quicker or slower. Without it a loaded city keeps its own, and a new one's are %v", DayLength))
+	flags.BoolVar(&config.Dev, "dev", false, "reload Tiled files and spritesheets from the assets directory as they change")
+	flags.BoolVar(&config.Hidden, "hidden", false, "hide the window, for running replays as regression tests. It still needs a display, like xvfb-run on CI")
+	flags.StringVar(&config.Load, "load", "", "load a saved city from this file")
+	flags.StringVar(&config.Mods, "mods", "mods", "load mods from this directory")
+	flags.StringVar(&config.Record, "record", "", "record every input to this file, to attach to bug reports")
+	flags.StringVar(&config.Replay, "replay", "", "play back a recording made with -record")
+	flags.StringVar(&config.Save, "save", "city.json", "save the city to this file")
+	flags.StringVar(&config.Screenshots, "screenshots", "screenshots", "save screenshots and timelapses in this directory")
+	flags.Int64Var(&config.Seed, "seed", 0, "seed the city's randomness, to reproduce a city. 0 picks a seed at random")
+	flags.StringVar(&config.Snapshot, "snapshot", "", "save the last frame to this PNG file on exit, like at the end of a replay")
+	flags.BoolVar(&config.Timelapse, "timelapse", false, "record a timelapse from the start, saved as a GIF on exit")
+	flags.Float64Var(&config.TimelapseHours, "timelapse-hours", 1, "capture a timelapse frame every this many in-game hours")
+	err := flags.Parse(args)
+	return config, err
+}
+
 // main initializes raylib, and drops into the Main Menu
 func main() {
-	Init()
+	// Tools for working on the game's assets run without opening a window
+	if len(os.Args) > 1 && os.Args[1] == "stamp" {
+		os.Exit(RunStampCommand(os.Args[2:], os.Stdout))
+	}
+
+	config, err := ParseConfig(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	if config.Dev && config.Assets == "" {
+		config.Assets = "."
+	}
+	if config.Assets != "" {
+		UseAssetOverride(config.Assets)
+	}
+
+	mods, errs := DiscoverMods(config.Mods)
+	for _, err := range errs {
+		fmt.Printf("Couldn't load mod: %v\n", err)
+	}
+	Mods = mods
+	problems := []string{}
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+
+	// A replay starts the city back up exactly as it was recorded, on the same size screen
+	if config.Replay != "" {
+		replay, err := OpenReplay(config.Replay)
+		if err != nil {
+			fmt.Printf("Couldn't open replay %v: %v\n", config.Replay, err)
+			os.Exit(1)
+		}
+		defer replay.Close()
+		Input.Replay = replay
+		config.Load = replay.Header.Load
+		config.Seed = replay.Header.Seed
+		config.DayLength = replay.Header.DayLength
+		ScreenX, ScreenY = replay.Header.ScreenX, replay.Header.ScreenY
+		// Only the mods the recording was made with are enabled, and without all of them it would play out differently
+		if missing := EnableOnly(Mods, replay.Header.Mods); len(missing) > 0 {
+			fmt.Printf("Couldn't play replay %v, it needs mods that aren't installed: %v\n", config.Replay, strings.Join(missing, ", "))
+			replay.Close()
+			os.Exit(1)
+		}
+	}
+
+	Init(config.Hidden)
 	ScreenX = int32(rl.GetScreenWidth())
 	ScreenY = int32(rl.GetScreenHeight())
 	GroundLevel = int(ScreenY - (ScreenY / 4))
+	WorldWidth = int(ScreenX) * streetScreens
+
+	// Replays skip the menu, since the menu isn't recorded
+	if Input.Replay != nil {
+		Run(config)
+		return
+	}
 
 	// Run the menu loop for the user
 	mainMenu := &Menu{Title: "_ Pixelopolis _", Buttons: make(map[int]string), ScreenX: ScreenX, ScreenY: ScreenY}
 	mainMenu.ButtonFunctions = make(map[int]func())
 	mainMenu.ButtonFunctions[0] = func() {
-		Run()
+		Run(config)
 		os.Exit(0)
 	}
 	mainMenu.Buttons[0] = "Start"
+	mainMenu.ButtonFunctions[1] = func() {
+		ModsMenu(Mods, problems).Loop()
+	}
+	mainMenu.Buttons[1] = "Mods"
 	mainMenu.Loop()
 }
 
 // Run runs our game loop
-func Run() {
-	engine := &Engine{Dosh: 1, Tax: 1.05, Lightcycle: rl.RayWhite}
+func Run(config Config) {
+	var save *CitySave
+	if config.Load != "" {
+		var err error
+		save, err = LoadCity(config.Load)
+		if err != nil {
+			fmt.Printf("Couldn't load city from %v: %v\n", config.Load, err)
+			return
+		}
+		config.Seed = save.Seed
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	// Print the seed so anyone reporting a bug can hand it over to reproduce their city
+	fmt.Printf("Seed: %v\n", config.Seed)
+
+	// Layer the mods' assets in before anything is loaded, so their replacements are picked up everywhere
+	mods, problems := ResolveModOrder(Mods)
+	problems = append(problems, ModConflicts(mods)...)
+	for _, problem := range problems {
+		fmt.Printf("Mods: %v\n", problem)
+	}
+	UseModAssets(ModAssets(mods))
+	modNames := []string{}
+	for _, mod := range mods {
+		modNames = append(modNames, mod.Manifest.Name)
+	}
+
+	if config.Record != "" {
+		recorder, err := NewRecorder(config.Record, ReplayHeader{DayLength: config.DayLength, Load: config.Load, Mods: modNames, ScreenX: ScreenX, ScreenY: ScreenY, Seed: config.Seed})
+		if err != nil {
+			fmt.Printf("Couldn't record to %v: %v\n", config.Record, err)
+			return
+		}
+		defer recorder.Close()
+		Input.Recorder = recorder
+	}
+
+	engine := &Engine{Dosh: 1, Tax: 1.05, Lightcycle: rl.RayWhite, DisasterFrequency: DisastersNormal, RNG: NewRNG(config.Seed), TimeScale: Normal}
+	// Start the city off in the morning
+	engine.Calendar = Calendar{DayLength: config.DayLength}
+	engine.Calendar.Ticks = int(float64(engine.Calendar.dayLength()) * Sunrise)
+	engine.Effects = append(engine.Effects, Disasters)
+	engine.Camera = NewCamera(float32(ScreenX))
 
 	// Group together some ground, grass, and skyline brushes to draw onto the screen for our background
 	type tile struct {
@@ -48,22 +210,23 @@ func Run() {
 	}
 	bgTiles := []tile{}
 
-	for x := 0; x < rl.GetScreenWidth(); x += 16 {
-		bgTiles = append(bgTiles, tile{171 + rand.Intn(4), x, GroundLevel})
+	for x := 0; x < WorldWidth; x += 16 {
+		bgTiles = append(bgTiles, tile{171 + engine.Rand(StreamTerrain).Intn(4), x, GroundLevel})
 	}
 
 	// Setup our Taxi
 	taxi := &Taxi{Passengers: 1, Engine: engine}
-	taxi.Sound = rl.LoadSound("assets/sounds/taxi.mp3")
+	taxi.Sound = Assets.Sound("assets/sounds/taxi.mp3")
 	taxi.Sprite.Init("assets/sprites/mega.png", 0, 1072, 96, 32)
+	taxi.Sprite.Clips = MegaClips("taxi")
 	taxi.Sprite.Speed = 4
 	// Spawn this off screen
-	taxi.Sprite.LevelX = float32(ScreenX + 96)
+	taxi.Sprite.LevelX = float32(WorldWidth + 96)
 	taxi.Sprite.LevelY = float32(GroundLevel)
-	engine.Entities = append(engine.Entities, taxi)
+	engine.Add(taxi)
 
 	// rl.InitAudioDevice()
-	backgroundMusic := rl.LoadMusicStream("assets/music/gameloop.mp3")
+	backgroundMusic := Assets.Music("assets/music/gameloop.mp3")
 	if Music {
 		rl.PlayMusicStream(backgroundMusic)
 	}
@@ -77,45 +240,152 @@ func Run() {
 	}))
 	ui.Toggles["drawPreview"] = false
 	engine.UI = ui
+	for _, problem := range ApplyMods(mods, engine, ui) {
+		fmt.Printf("Mods: %v\n", problem)
+	}
+
+	// The background is generated after the ground, so a seed always builds the same skyline
+	parallax, err := LoadParallax("assets/backgrounds/parallax.json", ui.Palettes[1], engine.Rand(StreamTerrain))
+	if err != nil {
+		fmt.Printf("Couldn't load the background: %v\n", err)
+		parallax = &Parallax{}
+	}
 
-	rain := &Rain{Color: rl.NewColor(57, 16, 90, 200)}
-	rain.Init()
-	engine.Entities = append(engine.Entities, rain)
+	weather := NewWeather(engine)
+	engine.Weather = weather
+	engine.Add(weather)
 
 	engine.Dosh = 300
+	if save != nil {
+		if err := save.Restore(engine, ui.Palettes[1]); err != nil {
+			fmt.Printf("Couldn't restore city from %v: %v\n", config.Load, err)
+			return
+		}
+	}
+	dayNight := NewDayNight(engine)
+	var hotReload *HotReload
+	if config.Dev {
+		hotReload = NewHotReload(engine, config.Assets)
+	}
 
+	capture := NewFrameCapture(ScreenX, ScreenY)
+	var timelapse *Timelapse
+	if config.Timelapse {
+		timelapse = NewTimelapse(config.TimelapseHours, engine.Calendar.dayLength(), engine.Calendar.Ticks)
+	}
+
+	queue := &RenderQueue{Camera: engine.Camera}
 	for !rl.WindowShouldClose() {
+		if err := Input.Begin(time.Duration(rl.GetFrameTime()*float32(time.Second)), engine.Calendar.Ticks); err != nil {
+			if err != io.EOF {
+				fmt.Printf("Replay stopped: %v\n", err)
+			}
+			break
+		}
 		rl.UpdateMusicStream(backgroundMusic)
+		rl.UpdateMusicStream(weather.Rain.Music)
+		if hotReload != nil {
+			hotReload.Update()
+		}
+		engine.Camera.Update()
+
+		// Engine entities are triggered through this call. It runs before drawing, so the frame shows where
+		// everything ended up
+		engine.Advance(Input.Frame.Elapsed)
+		if Input.KeyPressed(Keybindings["save"]) {
+			if err := engine.Save(config.Save); err != nil {
+				fmt.Printf("Couldn't save city to %v: %v\n", config.Save, err)
+			}
+		}
+		if Input.KeyPressed(Keybindings["timelapse"]) {
+			if timelapse == nil {
+				timelapse = NewTimelapse(config.TimelapseHours, engine.Calendar.dayLength(), engine.Calendar.Ticks)
+				fmt.Println("Recording a timelapse...")
+			} else {
+				saveTimelapse(timelapse, config.Screenshots, engine.Calendar.Ticks)
+				timelapse = nil
+			}
+		}
 
-		rl.BeginDrawing()
-		rl.ClearBackground(engine.Lightcycle)
+		// Frames are only drawn through the capture when they're going to be saved, or when the last one will be
+		screenshot := Input.KeyPressed(Keybindings["screenshot"])
+		capturing := screenshot || config.Snapshot != "" || (timelapse != nil && timelapse.Due(engine.Calendar.Ticks))
 
-		for _, t := range bgTiles {
-			if engine.Counter > 1000 {
-				ui.Palettes[3].Draw(t.brush, t.x, t.y)
-				rain.Color = rl.RayWhite
+		drawFrame(engine, queue, capture, capturing, func() {
+			queue.Push(LayerSky, 0, dayNight.DrawSky)
+			parallax.Render(queue, engine.Camera, Daylight(engine.Calendar.TimeOfDay()), engine.Lightcycle)
+			// The season decides what color the ground is
+			ground := ui.Palettes[engine.Calendar.Season().Palette()]
+			queue.Push(LayerGround, float32(GroundLevel), func() {
+				for _, t := range bgTiles {
+					ground.Draw(t.brush, t.x, t.y)
+				}
+			})
+			engine.Render(queue, engine.Camera.View())
+			queue.Push(LayerParticles, 0, ui.DrawPreview)
+			queue.Push(LayerLighting, 0, dayNight.DrawNight)
+			queue.Push(LayerUI, 0, ui.Draw)
+		})
+		if screenshot {
+			path := filepath.Join(config.Screenshots, fmt.Sprintf("city-%v.png", engine.Calendar.Ticks))
+			if err := SaveScreenshot(capture.Image(), path); err != nil {
+				fmt.Printf("Couldn't save screenshot: %v\n", err)
 			} else {
-				ui.Palettes[1].Draw(t.brush, t.x, t.y)
-				rain.Color = rl.NewColor(57, 16, 90, 200)
+				fmt.Printf("Saved %v\n", path)
 			}
 		}
-		// Engine entities are triggered through this call
-		engine.Draw()
-		engine.Update()
-		ui.Draw()
+		if timelapse != nil && timelapse.Due(engine.Calendar.Ticks) {
+			timelapse.Add(capture.Image(), engine.Calendar.Ticks)
+		}
 		ui.Update()
+		if err := Input.End(); err != nil {
+			fmt.Printf("Couldn't record input: %v\n", err)
+		}
 
 		rl.EndDrawing()
 	}
 
+	if Input.Replay != nil {
+		// Print where the replay ended up, so a hidden run can be compared against a known good one
+		fmt.Printf("Replay finished at tick %v: dosh %.2f, population %v / %v, buildings %v\n",
+			engine.Calendar.Ticks, engine.Dosh, engine.Population, engine.PopulationMax, len(engine.Buildings()))
+	}
+	if config.Snapshot != "" {
+		if err := SaveScreenshot(capture.Image(), config.Snapshot); err != nil {
+			fmt.Printf("Couldn't save snapshot to %v: %v\n", config.Snapshot, err)
+		}
+	}
+	if timelapse != nil {
+		saveTimelapse(timelapse, config.Screenshots, engine.Calendar.Ticks)
+	}
+	capture.Release()
 	rl.CloseWindow()
 }
 
-// Init sets up our keybindings
-func Init() {
-	rl.SetConfigFlags(rl.FlagMsaa4xHint)
+// saveTimelapse writes the timelapse to a GIF named after the ticks it covers
+func saveTimelapse(timelapse *Timelapse, dir string, tick int) {
+	path := filepath.Join(dir, fmt.Sprintf("timelapse-%v-%v.gif", timelapse.Start, tick))
+	if err := timelapse.Save(path); err != nil {
+		fmt.Printf("Couldn't save timelapse: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved %v\n", path)
+}
+
+// flagWindowHidden is raylib's FLAG_WINDOW_HIDDEN, which the Go bindings don't export
+const flagWindowHidden = 128
+
+// Init sets up our window and keybindings. A hidden window runs as fast as it can
+func Init(hidden bool) {
+	if hidden {
+		rl.SetConfigFlags(flagWindowHidden)
+	} else {
+		rl.SetConfigFlags(rl.FlagMsaa4xHint)
+	}
 	rl.InitWindow(ScreenX, ScreenY, "Pixelopolis")
-	rl.SetTargetFPS(60)
+	if !hidden {
+		rl.SetTargetFPS(60)
+	}
 
 	Keybindings = make(map[string]int32)
 	Keybindings["forward"] = rl.KeyW
@@ -124,4 +394,13 @@ func Init() {
 	Keybindings["right"] = rl.KeyD
 	Keybindings["space"] = rl.KeySpace
 	Keybindings["exit"] = rl.KeyEscape
+	Keybindings["pause"] = rl.KeyP
+	Keybindings["normal"] = rl.KeyOne
+	Keybindings["fast"] = rl.KeyTwo
+	Keybindings["fastest"] = rl.KeyThree
+	Keybindings["step"] = rl.KeyPeriod
+	Keybindings["save"] = rl.KeyF5
+	Keybindings["assets"] = rl.KeyF3
+	Keybindings["timelapse"] = rl.KeyF9
+	Keybindings["screenshot"] = rl.KeyF12
 }
@@ -7,6 +7,8 @@ import (
 
 var (
 	backgroundMusic rl.Music
+	// menuInitialized is true once the menu's assets are loaded, so menus opened from other menus don't reload them
+	menuInitialized bool
 )
 
 // Menu is an abstraction for menus
@@ -16,13 +18,20 @@ type Menu struct {
 	Buttons                      map[int]string
 	ButtonXOffset, ButtonYOffset int
 	ButtonFunctions              map[int]func()
-	Effects                      []func()
-	ScreenX, ScreenY             int32
+	// Done closes the menu, returning from Loop
+	Done             bool
+	Effects          []func()
+	ScreenX, ScreenY int32
+	// Spacing is the gap between buttons. It defaults to a fifth of the screen
+	Spacing float32
 }
 
 // Loop starts a loop for the main menu
 func (menu *Menu) Loop() {
-	MenuInit()
+	if !menuInitialized {
+		MenuInit()
+		menuInitialized = true
+	}
 	if Music {
 		rl.PlayMusicStream(backgroundMusic)
 	}
@@ -30,12 +39,18 @@ func (menu *Menu) Loop() {
 	var background rl.Texture2D
 	bgDefined := menu.BackgroundImagePath != ""
 	if bgDefined {
-		background = rl.LoadTexture(menu.BackgroundImagePath)
+		background = Assets.Texture(menu.BackgroundImagePath)
 	}
 
-	rain := &Rain{Color: rl.LightGray}
+	rain := NewRain(rl.LightGray)
 	menu.Effects = append(menu.Effects, rain.Draw, rain.Update)
-	for !rl.WindowShouldClose() {
+	spacing := menu.Spacing
+	if spacing == 0 {
+		spacing = float32(menu.ScreenY / 5)
+	}
+
+	menu.Done = false
+	for !menu.Done && !rl.WindowShouldClose() {
 		if Music {
 			rl.UpdateMusicStream(backgroundMusic)
 		}
@@ -51,7 +66,7 @@ func (menu *Menu) Loop() {
 		}
 
 		for i, b := range menu.Buttons {
-			button := raygui.Button(rl.NewRectangle(float32(menu.ScreenX/2)-40, float32(menu.ScreenY)-float32(menu.ScreenY/5)*float32(i+1), 100, 30), b)
+			button := raygui.Button(rl.NewRectangle(float32(menu.ScreenX/2)-40, float32(menu.ScreenY)-spacing*float32(i+1), 100, 30), b)
 			if button {
 				menu.ButtonFunctions[i]()
 			}
@@ -68,11 +83,11 @@ func (menu *Menu) Loop() {
 // MenuInit initializes assets created for the menu
 func MenuInit() {
 	rl.InitAudioDevice()
-	backgroundMusic = rl.LoadMusicStream("assets/music/menudrumroll.mp3")
+	backgroundMusic = Assets.Music("assets/music/menudrumroll.mp3")
 }
 
 // MenuClose cleans up menu assets
 func MenuClose() {
-	rl.UnloadMusicStream(backgroundMusic)
+	Assets.ReleaseMusic("assets/music/menudrumroll.mp3")
 	rl.CloseAudioDevice()
 }
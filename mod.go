@@ -0,0 +1,441 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ModManifestFile is the name of the manifest at the root of every mod
+const ModManifestFile = "mod.json"
+
+// ModManifest describes what a mod adds to the game. Assets are laid out like the base game's, so a mod that ships
+// assets/sprites/mega.png replaces the base spritesheet, and the paths below are given the same way
+type ModManifest struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// After lists mods that have to be loaded before this one
+	After []string `json:"after"`
+	// Priority orders mods that don't depend on each other, lowest first. Later mods win any conflicts
+	Priority  int           `json:"priority"`
+	Buildings []ModBuilding `json:"buildings"`
+	// Events cover dialogs too. An event with nothing but text is a dialog shown when the city starts
+	Events   []ModEvent   `json:"events"`
+	Palettes []ModPalette `json:"palettes"`
+}
+
+// ModBuilding is a building a mod adds to the catalog
+type ModBuilding struct {
+	Name string `json:"name"`
+	// Label is the text on its build button
+	Label string  `json:"label"`
+	Cost  float64 `json:"cost"`
+	// Palette is the name of one of the mod's palettes to draw it with. Leave it empty to use the city's
+	Palette    string `json:"palette"`
+	Population int    `json:"population"`
+	// Tiled is the Tiled JSON file it's drawn from
+	Tiled string `json:"tiled"`
+}
+
+// ModPalette is a tilesheet a mod's buildings can be drawn with
+type ModPalette struct {
+	Name string `json:"name"`
+	// Ramp is the shades the spritesheet is drawn in, darkest first, like "#555763". With it, the palette is recolored
+	// into the preview tints. Without it, its buildings are previewed as they are
+	Ramp        []string `json:"ramp"`
+	Spritesheet string   `json:"spritesheet"`
+	TileHeight  int      `json:"tileHeight"`
+	TileWidth   int      `json:"tileWidth"`
+	// Margin and Spacing are the gaps around and between the tiles, as set on the tileset in Tiled
+	Margin  int `json:"margin"`
+	Spacing int `json:"spacing"`
+}
+
+// ModEvent shows a dialog once all of its conditions are met
+type ModEvent struct {
+	// After is how long into the game to wait, like "2m"
+	After      string  `json:"after"`
+	Dosh       float64 `json:"dosh"`
+	Population int     `json:"population"`
+	// Sound plays when the event triggers. Leave it empty for the usual chime
+	Sound string `json:"sound"`
+	Text  string `json:"text"`
+}
+
+// Mod is a mod found on disk, either a directory or a zip
+type Mod struct {
+	Enabled  bool
+	FS       fs.FS
+	Manifest ModManifest
+	Path     string
+
+	closer io.Closer
+}
+
+// Close closes the mod's zip, if it has one
+func (m *Mod) Close() error {
+	if m.closer == nil {
+		return nil
+	}
+	return m.closer.Close()
+}
+
+// Mods are the mods discovered at startup
+var Mods []*Mod
+
+// LoadMod opens a mod directory or zip and reads its manifest. Mods start out enabled
+func LoadMod(path string) (*Mod, error) {
+	mod := &Mod{Enabled: true, Path: path}
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		mod.FS, mod.closer = archive, archive
+	} else {
+		mod.FS = os.DirFS(path)
+	}
+
+	data, err := fs.ReadFile(mod.FS, ModManifestFile)
+	if err == nil {
+		err = json.Unmarshal(data, &mod.Manifest)
+	}
+	if err == nil && mod.Manifest.Name == "" {
+		err = fmt.Errorf("%v has no name", ModManifestFile)
+	}
+	if err != nil {
+		mod.Close()
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return mod, nil
+}
+
+// DiscoverMods loads every mod in the directory. Mods that can't be loaded are skipped and reported
+func DiscoverMods(dir string) ([]*Mod, []error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	mods := []*Mod{}
+	errs := []error{}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.EqualFold(filepath.Ext(entry.Name()), ".zip") {
+			continue
+		}
+		mod, err := LoadMod(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mods = append(mods, mod)
+	}
+	return mods, errs
+}
+
+// ResolveModOrder sorts the enabled mods into the order they load in: by priority then name, with every mod after the
+// mods it depends on. Mods missing a dependency, or stuck in a dependency cycle, are left out and reported
+func ResolveModOrder(mods []*Mod) ([]*Mod, []string) {
+	problems := []string{}
+	byName := make(map[string]*Mod)
+	candidates := []*Mod{}
+	for _, mod := range mods {
+		if !mod.Enabled {
+			continue
+		}
+		if _, ok := byName[mod.Manifest.Name]; ok {
+			problems = append(problems, fmt.Sprintf("%v is installed twice, skipping %v", mod.Manifest.Name, mod.Path))
+			continue
+		}
+		byName[mod.Manifest.Name] = mod
+		candidates = append(candidates, mod)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].Manifest, candidates[j].Manifest
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.Name < b.Name
+	})
+
+	// Drop anything missing a dependency, and anything depending on that, until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, mod := range candidates {
+			if byName[mod.Manifest.Name] == nil {
+				continue
+			}
+			for _, dependency := range mod.Manifest.After {
+				if byName[dependency] == nil {
+					problems = append(problems, fmt.Sprintf("%v needs %v, which isn't enabled", mod.Manifest.Name, dependency))
+					delete(byName, mod.Manifest.Name)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	// Repeatedly take the first mod in priority order whose dependencies have all loaded
+	ordered := []*Mod{}
+	loaded := make(map[string]bool)
+	remaining := []*Mod{}
+	for _, mod := range candidates {
+		if byName[mod.Manifest.Name] != nil {
+			remaining = append(remaining, mod)
+		}
+	}
+	for len(remaining) > 0 {
+		next := -1
+		for i, mod := range remaining {
+			ready := true
+			for _, dependency := range mod.Manifest.After {
+				ready = ready && loaded[dependency]
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			for _, mod := range remaining {
+				problems = append(problems, fmt.Sprintf("%v is in a dependency cycle", mod.Manifest.Name))
+			}
+			break
+		}
+		loaded[remaining[next].Manifest.Name] = true
+		ordered = append(ordered, remaining[next])
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return ordered, problems
+}
+
+// EnableOnly enables the named mods and disables the rest, and returns any names that none of the mods have
+func EnableOnly(mods []*Mod, names []string) []string {
+	found := make(map[string]bool)
+	for _, mod := range mods {
+		mod.Enabled = false
+		for _, name := range names {
+			if mod.Manifest.Name == name {
+				mod.Enabled = true
+				found[name] = true
+			}
+		}
+	}
+	missing := []string{}
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// ModConflicts lists everything more than one mod provides, or that a mod replaces in the base game. For assets the mod
+// loaded last wins, but buildings can't be replaced, so only the first of each name is added
+func ModConflicts(ordered []*Mod) []string {
+	conflicts := []string{}
+	buildings := make(map[string]string)
+	for name := range Catalog {
+		buildings[name] = "the base game"
+	}
+	files := make(map[string]string)
+	for _, mod := range ordered {
+		name := mod.Manifest.Name
+		for _, building := range mod.Manifest.Buildings {
+			if previous, ok := buildings[building.Name]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%v's %v building is skipped, %v already has one", name, building.Name, previous))
+				continue
+			}
+			buildings[building.Name] = name
+		}
+		fs.WalkDir(mod.FS, "assets", func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if previous, ok := files[path]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%v replaces %v from %v", name, path, previous))
+			}
+			files[path] = name
+			return nil
+		})
+	}
+	return conflicts
+}
+
+// ModAssets returns the mods' assets as layers for UseModAssets, so the mod loaded last wins any conflicts
+func ModAssets(ordered []*Mod) []fs.FS {
+	layers := []fs.FS{}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		layers = append(layers, ordered[i].FS)
+	}
+	return layers
+}
+
+// ApplyMods adds the mods' palettes, buildings and events to the city, in load order, and returns the problems with
+// anything it had to skip. Their assets should already be layered in with UseModAssets
+func ApplyMods(ordered []*Mod, engine *Engine, ui *UI) []string {
+	problems := []string{}
+	palettes := make(map[string]*Palette)
+	for _, mod := range ordered {
+		for _, p := range mod.Manifest.Palettes {
+			palette := NewSpacedPalette(p.Spritesheet, p.TileHeight, p.TileWidth, p.Margin, p.Spacing)
+			if len(p.Ramp) > 0 {
+				ramp, err := ParseColorRamp(p.Ramp)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("skipping the preview tints for %v in %v: %v", p.Name, mod.Manifest.Name, err))
+				} else {
+					palette.AddPreviewVariants(ramp)
+				}
+			}
+			palettes[p.Name] = palette
+		}
+		for _, b := range mod.Manifest.Buildings {
+			if err := checkModBuildingName(b.Name, ui); err != nil {
+				problems = append(problems, fmt.Sprintf("skipping a building in %v: %v", mod.Manifest.Name, err))
+				continue
+			}
+			build, err := modBuilding(b, palettes[b.Palette])
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("skipping the %v building in %v: %v", b.Name, mod.Manifest.Name, err))
+				continue
+			}
+			Catalog[b.Name] = build
+			label := b.Label
+			if label == "" {
+				label = fmt.Sprintf("$%v - %v", b.Cost, b.Name)
+			}
+			ui.AddBuildingButton(b.Name, label)
+		}
+		for _, e := range mod.Manifest.Events {
+			event, err := modEvent(e, engine)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("skipping an event in %v: %v", mod.Manifest.Name, err))
+				continue
+			}
+			ui.Events = append(ui.Events, event)
+		}
+	}
+	return problems
+}
+
+// checkModBuildingName returns an error if a mod's building can't be added under its name: it's already in the catalog,
+// from the base game or an earlier mod, or it's named like one of the UI's own buttons
+func checkModBuildingName(name string, ui *UI) error {
+	if name == "" {
+		return fmt.Errorf("it has no name")
+	}
+	if _, ok := Catalog[name]; ok {
+		return fmt.Errorf("there's already a %v building", name)
+	}
+	if _, ok := ui.Buttons[name]; ok {
+		return fmt.Errorf("%v is reserved for the UI", name)
+	}
+	return nil
+}
+
+// modBuilding returns the catalog entry for a mod's building, or an error if its Tiled file won't load
+func modBuilding(b ModBuilding, palette *Palette) (func(*Engine, *Palette) *Building, error) {
+	if _, err := GetStampFromTiledFile(b.Tiled); err != nil {
+		return nil, fmt.Errorf("couldn't load %v: %v", b.Tiled, err)
+	}
+	return func(engine *Engine, _ *Palette) *Building {
+		// It loaded when the mod was applied, so this only fails if the file's gone since
+		stamp, err := GetStampFromTiledFile(b.Tiled)
+		if err != nil {
+			fmt.Printf("Couldn't load %v for %v: %v\n", b.Tiled, b.Name, err)
+		}
+		stamp.Palette = palette
+		return &Building{
+			Cost:       b.Cost,
+			Engine:     engine,
+			Filepath:   b.Tiled,
+			Name:       b.Name,
+			Palette:    palette,
+			Population: b.Population,
+			Stamp:      stamp,
+		}
+	}, nil
+}
+
+// modEvent turns a mod's event into a dialog that shows once its conditions are met
+func modEvent(e ModEvent, engine *Engine) (*Event, error) {
+	after := time.Duration(0)
+	if e.After != "" {
+		var err error
+		if after, err = time.ParseDuration(e.After); err != nil {
+			return nil, err
+		}
+	}
+
+	event := NewEventDuration(after, func() {
+		NewDialog(e.Text, 48)
+	})
+	timer := event.Trigger
+	event.Trigger = func() bool {
+		return timer() && engine.Dosh >= e.Dosh && engine.Population >= e.Population
+	}
+	if e.Sound != "" {
+		event.Release()
+		event.Sound, event.SoundPath = Assets.Sound(e.Sound), e.Sound
+	}
+	return event, nil
+}
+
+// ModsMenu lists the mods so they can be turned on and off before starting the city, along with any conflicts
+func ModsMenu(mods []*Mod, problems []string) *Menu {
+	menu := &Menu{Title: "_ Mods _", Buttons: make(map[int]string), ScreenX: ScreenX, ScreenY: ScreenY, Spacing: 40}
+	menu.ButtonFunctions = make(map[int]func())
+
+	label := func(mod *Mod) string {
+		check := "[ ]"
+		if mod.Enabled {
+			check = "[x]"
+		}
+		return fmt.Sprintf("%v %v %v", check, mod.Manifest.Name, mod.Manifest.Version)
+	}
+	notes := problems
+	refresh := func() {
+		ordered, orderProblems := ResolveModOrder(mods)
+		notes = append(append(append([]string{}, problems...), orderProblems...), ModConflicts(ordered)...)
+	}
+	refresh()
+
+	menu.Buttons[0] = "Back"
+	menu.ButtonFunctions[0] = func() {
+		menu.Done = true
+	}
+	for i, mod := range mods {
+		i, mod := i+1, mod
+		menu.Buttons[i] = label(mod)
+		menu.ButtonFunctions[i] = func() {
+			mod.Enabled = !mod.Enabled
+			menu.Buttons[i] = label(mod)
+			refresh()
+		}
+	}
+	if len(mods) == 0 {
+		notes = append(notes, "No mods found. Put them in the mods directory")
+	}
+
+	menu.Effects = append(menu.Effects, func() {
+		for i, note := range notes {
+			rl.DrawText(note, 20, 200+int32(i)*20, 18, rl.Gold)
+		}
+	})
+	return menu
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testMod returns an enabled mod with the given name, priority and dependencies
+func testMod(name string, priority int, after ...string) *Mod {
+	return &Mod{
+		Enabled:  true,
+		FS:       fstest.MapFS{},
+		Manifest: ModManifest{Name: name, Priority: priority, After: after},
+	}
+}
+
+// names returns the names of the mods, in order
+func names(mods []*Mod) []string {
+	list := []string{}
+	for _, mod := range mods {
+		list = append(list, mod.Manifest.Name)
+	}
+	return list
+}
+
+func TestResolveModOrder(t *testing.T) {
+	disabled := testMod("disabled", 0)
+	disabled.Enabled = false
+	mods := []*Mod{
+		testMod("zoning", 0, "roads"),
+		testMod("roads", 5),
+		testMod("aliens", 0),
+		testMod("trains", 1),
+		testMod("orphan", 0, "disabled"),
+		testMod("grandorphan", 0, "orphan"),
+		testMod("chicken", 0, "egg"),
+		testMod("egg", 0, "chicken"),
+		disabled,
+	}
+
+	ordered, problems := ResolveModOrder(mods)
+	// Priority then name, but zoning waits for roads
+	assert.Equal(t, []string{"aliens", "trains", "roads", "zoning"}, names(ordered))
+	assert.Contains(t, problems, "orphan needs disabled, which isn't enabled")
+	assert.Contains(t, problems, "grandorphan needs orphan, which isn't enabled")
+	assert.Contains(t, problems, "chicken is in a dependency cycle")
+	assert.Contains(t, problems, "egg is in a dependency cycle")
+}
+
+func TestEnableOnly(t *testing.T) {
+	mods := []*Mod{testMod("roads", 0), testMod("trains", 0), testMod("aliens", 0)}
+	missing := EnableOnly(mods, []string{"trains", "zoning", "roads"})
+	assert.Equal(t, []string{"zoning"}, missing)
+	assert.True(t, mods[0].Enabled)
+	assert.True(t, mods[1].Enabled)
+	assert.False(t, mods[2].Enabled)
+}
+
+func TestModConflicts(t *testing.T) {
+	first := testMod("first", 0)
+	first.FS = fstest.MapFS{"assets/sprites/mega.png": {Data: []byte("first")}}
+	first.Manifest.Buildings = []ModBuilding{{Name: "house"}, {Name: "tower"}}
+	second := testMod("second", 1)
+	second.FS = fstest.MapFS{"assets/sprites/mega.png": {Data: []byte("second")}}
+	second.Manifest.Buildings = []ModBuilding{{Name: "tower"}}
+
+	assert.Equal(t, []string{
+		"first's house building is skipped, the base game already has one",
+		"second's tower building is skipped, first already has one",
+		"second replaces assets/sprites/mega.png from first",
+	}, ModConflicts([]*Mod{first, second}))
+}
+
+func TestApplyModBuildings(t *testing.T) {
+	defer func(catalog map[string]func(*Engine, *Palette) *Building) { Catalog = catalog }(Catalog)
+	Catalog = map[string]func(*Engine, *Palette) *Building{"house": GetHouse}
+	ui := &UI{Buttons: map[string]*Button{"pause": {}}}
+
+	first := testMod("first", 0)
+	first.Manifest.Buildings = []ModBuilding{
+		{Name: "shack", Tiled: "assets/buildings/slum/2.json"},
+		{Name: "house", Tiled: "assets/buildings/slum/2.json"},
+		{Name: "pause", Tiled: "assets/buildings/slum/2.json"},
+		{Name: "ghost", Tiled: "assets/buildings/ghost.json"},
+	}
+	second := testMod("second", 1)
+	second.Manifest.Buildings = []ModBuilding{{Name: "shack", Tiled: "assets/buildings/slum/1.json"}}
+
+	problems := ApplyMods([]*Mod{first, second}, &Engine{}, ui)
+	assert.Len(t, problems, 4)
+	assert.Contains(t, problems, "skipping a building in first: there's already a house building")
+	assert.Contains(t, problems, "skipping a building in first: pause is reserved for the UI")
+	assert.Contains(t, problems, "skipping a building in second: there's already a shack building")
+
+	// Only the first shack is added, with a build button that can't be mistaken for the UI's own
+	assert.Len(t, Catalog, 2)
+	assert.Equal(t, "assets/buildings/slum/2.json", Catalog["shack"](&Engine{}, nil).Filepath)
+	assert.Contains(t, ui.Buttons, "build:shack")
+	assert.Len(t, ui.Buttons, 2)
+}
+
+func TestLoadMod(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pixelopolis")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	manifest := []byte(`{"name": "zipped", "version": "1.0", "priority": 2}`)
+	archive, err := os.Create(filepath.Join(dir, "zipped.zip"))
+	assert.NoError(t, err)
+	writer := zip.NewWriter(archive)
+	file, err := writer.Create(ModManifestFile)
+	assert.NoError(t, err)
+	file.Write(manifest)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, archive.Close())
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "unnamed"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "unnamed", ModManifestFile), []byte(`{}`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a mod"), 0644))
+
+	mods, errs := DiscoverMods(dir)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, []string{"zipped"}, names(mods))
+	assert.Equal(t, 2, mods[0].Manifest.Priority)
+	assert.True(t, mods[0].Enabled)
+	assert.NoError(t, mods[0].Close())
+
+	mods, errs = DiscoverMods(filepath.Join(dir, "missing"))
+	assert.Empty(t, mods)
+	assert.Empty(t, errs)
+}
@@ -11,57 +11,81 @@ type Brush struct {
 
 // Palette represents a bunch of brushes in a texture
 type Palette struct {
+	// Base is the spritesheet a recolored variant was generated from
+	Base    string
 	Brushes map[int]Brush
 	// Filepath to sprite sheet
 	Spritesheet string
 	Texture     rl.Texture2D
+	// Variants are recolored copies of the palette, by name, like the preview tints
+	Variants map[string]*Palette
 	// Set width and Height so we can automatically parse our Texture into a tilesheet
 	Width, Height         int
 	TileWidth, TileHeight int
+	// Margin is the gap around the edge of the tilesheet, and Spacing the gap between its tiles, as Tiled has them
+	Margin, Spacing int
+	// Recolor is how a variant was recolored from its base spritesheet
+	Recolor Recolor
+	// Generation counts how many times the spritesheet's been reloaded, so anything baked from it is baked again
+	Generation int
 }
 
 // NewPalette is a factory that takes a filepath to a tilesheet, and the tilesheet's tile width and height
 func NewPalette(filepath string, tileHeight, tileWidth int) *Palette {
-	img := rl.LoadImage(filepath)
+	return NewSpacedPalette(filepath, tileHeight, tileWidth, 0, 0)
+}
 
-	// Declare brushMap, cover any sort of possible edge case here. We start at 1 instead of 0 due to the
-	// same behavior being embedded in Tiled in its tileset data handling
-	brushMap := make(map[int]Brush)
-	brushMap[0] = Brush{0, 0, 0, 0}
-
-	xCycles := img.Width / int32(tileWidth)
-	yCycles := img.Height / int32(tileHeight)
-	length := xCycles * yCycles
-	x := 0
-	y := 0
-	for i := 0; i < int(length); i++ {
-		if i > 0 {
-			x = i % tileWidth
-		}
-		brushMap[i] = Brush{float32(x * tileWidth), float32(y * tileHeight), float32(tileWidth), float32(tileHeight)}
-
-		if x > 0 && (x+1)%tileWidth == 0 {
-			y++
-		}
-		x++
-	}
+// NewSpacedPalette is NewPalette for tilesheets with a margin around their edge or spacing between their tiles
+func NewSpacedPalette(filepath string, tileHeight, tileWidth, margin, spacing int) *Palette {
+	texture := Assets.Texture(filepath)
 
 	return &Palette{
-		Brushes:     brushMap,
+		Brushes:     BrushMap(int(texture.Width), int(texture.Height), tileWidth, tileHeight, margin, spacing),
 		Spritesheet: filepath,
-		Texture:     rl.LoadTextureFromImage(img),
-		Width:       int(img.Width),
-		Height:      int(img.Height),
+		Texture:     texture,
+		Width:       int(texture.Width),
+		Height:      int(texture.Height),
 		TileHeight:  tileHeight,
 		TileWidth:   tileWidth,
+		Margin:      margin,
+		Spacing:     spacing,
 	}
 }
 
+// BrushMap slices a tilesheet of the given size into brushes, numbered left to right then top to bottom from 0, the
+// same way Tiled numbers a tileset's tiles before adding its firstgid. Tiles that don't fully fit in the sheet are left
+// out, like Tiled does
+func BrushMap(imageWidth, imageHeight, tileWidth, tileHeight, margin, spacing int) map[int]Brush {
+	brushMap := make(map[int]Brush)
+	if tileWidth <= 0 || tileHeight <= 0 {
+		return brushMap
+	}
+
+	columns := (imageWidth - margin + spacing) / (tileWidth + spacing)
+	rows := (imageHeight - margin + spacing) / (tileHeight + spacing)
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			brushMap[row*columns+column] = Brush{
+				XPos:   float32(margin + column*(tileWidth+spacing)),
+				YPos:   float32(margin + row*(tileHeight+spacing)),
+				Width:  float32(tileWidth),
+				Height: float32(tileHeight),
+			}
+		}
+	}
+	return brushMap
+}
+
 // Draw uses the given brush at an X,Y point
 func (p *Palette) Draw(brush, x, y int) {
+	p.DrawTinted(brush, x, y, rl.White)
+}
+
+// DrawTinted uses the given brush at an X,Y point, multiplied by the color
+func (p *Palette) DrawTinted(brush, x, y int, color rl.Color) {
 	rectangle := rl.NewRectangle(p.Brushes[brush].XPos, p.Brushes[brush].YPos, p.Brushes[brush].Width, p.Brushes[brush].Height)
 	position := rl.NewVector2(float32(x), float32(y))
-	rl.DrawTextureRec(p.Texture, rectangle, position, rl.White)
+	drawTextureRec(p.Texture, rectangle, position, color)
 }
 
 // Update loads the brushes into textures so they can be drawn to the sceen
@@ -82,8 +106,13 @@ type Stamp struct {
 
 // Draw renders the stamp from the given x, y coordinates
 func (s *Stamp) Draw() {
+	s.DrawAt(s.LevelX, s.LevelY)
+}
+
+// DrawAt renders the stamp brush by brush with its top left at x, y rather than where it stands
+func (s *Stamp) DrawAt(x, y float32) {
 	for _, i := range s.DrawCoords {
-		s.Palette.Draw(i.Brush, int(s.LevelX+i.XOffset), int(s.LevelY+i.YOffset))
+		s.Palette.Draw(i.Brush, int(x+i.XOffset), int(y+i.YOffset))
 	}
 }
 
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrushMap(t *testing.T) {
+	// The projectmute tileset, 16 columns of 16x16 tiles
+	brushes := BrushMap(256, 208, 16, 16, 0, 0)
+	assert.Len(t, brushes, 16*13)
+	assert.Equal(t, Brush{0, 0, 16, 16}, brushes[0])
+	assert.Equal(t, Brush{240, 0, 16, 16}, brushes[15])
+	assert.Equal(t, Brush{0, 16, 16, 16}, brushes[16])
+	// The slum's wall, which Tiled saves as 110
+	assert.Equal(t, Brush{208, 96, 16, 16}, brushes[109])
+}
+
+func TestBrushMapNonSquare(t *testing.T) {
+	// 28 columns, like mega.png, with tiles taller than they are wide. Leftover pixels don't make a tile
+	brushes := BrushMap(448+10, 1152, 16, 32, 0, 0)
+	assert.Len(t, brushes, 28*36)
+	assert.Equal(t, Brush{432, 0, 16, 32}, brushes[27])
+	assert.Equal(t, Brush{0, 32, 16, 32}, brushes[28])
+	assert.Equal(t, Brush{16, 1120, 16, 32}, brushes[28*35+1])
+}
+
+func TestBrushMapMarginAndSpacing(t *testing.T) {
+	// 3 columns and 2 rows: 1 + 3*16 + 2*2 = 53 wide and 1 + 2*16 + 2 = 35 tall, plus a trailing margin
+	brushes := BrushMap(54, 36, 16, 16, 1, 2)
+	assert.Len(t, brushes, 6)
+	assert.Equal(t, Brush{1, 1, 16, 16}, brushes[0])
+	assert.Equal(t, Brush{19, 1, 16, 16}, brushes[1])
+	assert.Equal(t, Brush{37, 1, 16, 16}, brushes[2])
+	assert.Equal(t, Brush{1, 19, 16, 16}, brushes[3])
+	assert.Equal(t, Brush{37, 19, 16, 16}, brushes[5])
+}
+
+func TestBrushMapEmpty(t *testing.T) {
+	assert.Empty(t, BrushMap(8, 8, 16, 16, 0, 0))
+	assert.Empty(t, BrushMap(256, 256, 0, 16, 0, 0))
+}
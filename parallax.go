@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ParallaxLayer is one strip of the background, like the distant skyline. It's generated from the city's seed when the
+// city starts, and repeats every Period pixels
+type ParallaxLayer struct {
+	Name string `json:"name"`
+	// Kind is what the layer is made of: "towers" for a ruined skyline, "rubble" for tiles from the tileset, and
+	// "clouds"
+	Kind string `json:"kind"`
+	// Rate is how fast the layer scrolls with the camera. 0 stays put like the sky, 1 moves with the street
+	Rate float32 `json:"rate"`
+	// Drift scrolls the layer on its own, in pixels a second, like clouds blowing along
+	Drift float32 `json:"drift"`
+	// Base is how far above the street the bottom of the layer sits
+	Base  float32 `json:"base"`
+	Color string  `json:"color"`
+	// Haze is how far the layer fades into the sky, from 0 to 1. Distant layers are hazier
+	Haze float32 `json:"haze"`
+	// Each piece of the layer is between the min and max size, with up to Gap pixels between them
+	MinWidth  float32 `json:"minWidth"`
+	MaxWidth  float32 `json:"maxWidth"`
+	MinHeight float32 `json:"minHeight"`
+	MaxHeight float32 `json:"maxHeight"`
+	Gap       float32 `json:"gap"`
+	Period    float32 `json:"period"`
+	// Brushes are the tiles rubble is picked from
+	Brushes []int `json:"brushes"`
+
+	color  rl.Color
+	pieces []parallaxPiece
+}
+
+// parallaxPiece is a tower, a cloud or a tile in a layer, positioned along one period of it
+type parallaxPiece struct {
+	Area  rl.Rectangle
+	Brush int
+	// Steps are narrower blocks stacked on a tower, so the skyline has broken, uneven tops
+	Steps []rl.Rectangle
+}
+
+// Parallax is the background behind the street, in layers that scroll slower the further away they are
+type Parallax struct {
+	Layers []*ParallaxLayer `json:"layers"`
+	// Palette is what rubble tiles are drawn from
+	Palette *Palette `json:"-"`
+}
+
+// LoadParallax reads the background's layers from the assets, and generates each of them from the random source
+func LoadParallax(filepath string, palette *Palette, rng *rand.Rand) (*Parallax, error) {
+	data, err := ReadAsset(filepath)
+	if err != nil {
+		return nil, err
+	}
+	parallax := &Parallax{Palette: palette}
+	if err := json.Unmarshal(data, parallax); err != nil {
+		return nil, fmt.Errorf("%v: %v", filepath, err)
+	}
+	for _, layer := range parallax.Layers {
+		if err := layer.Generate(rng); err != nil {
+			return nil, fmt.Errorf("%v: layer %v: %v", filepath, layer.Name, err)
+		}
+	}
+	return parallax, nil
+}
+
+// Generate lays the layer's pieces out along one period
+func (l *ParallaxLayer) Generate(rng *rand.Rand) error {
+	ramp, err := ParseColorRamp([]string{l.Color})
+	if err != nil {
+		return err
+	}
+	l.color = ramp[0]
+	if l.Period <= 0 || l.MaxWidth <= 0 {
+		return fmt.Errorf("needs a period and a max width")
+	}
+	if l.Kind == "rubble" && len(l.Brushes) == 0 {
+		return fmt.Errorf("rubble needs brushes to pick from")
+	}
+
+	between := func(min, max float32) float32 {
+		return min + rng.Float32()*(max-min)
+	}
+	l.pieces = nil
+	for x := between(0, l.Gap); x < l.Period; {
+		piece := parallaxPiece{}
+		width, height := between(l.MinWidth, l.MaxWidth), between(l.MinHeight, l.MaxHeight)
+		switch l.Kind {
+		case "rubble":
+			piece.Brush = l.Brushes[rng.Intn(len(l.Brushes))]
+			width, height = 16, 16
+		case "towers":
+			// Ruined towers lose their tops unevenly
+			for top, steps := height, rng.Intn(3); steps > 0; steps-- {
+				step := between(width/4, width*3/4)
+				offset := between(0, width-step)
+				rise := between(4, height/4)
+				piece.Steps = append(piece.Steps, rl.NewRectangle(x+offset, top, step, rise))
+				top += rise
+			}
+		}
+		piece.Area = rl.NewRectangle(x, 0, width, height)
+		l.pieces = append(l.pieces, piece)
+		x += width + between(0, l.Gap)
+	}
+	return nil
+}
+
+// Render queues each layer behind the street, furthest first, scrolled for the camera and tinted for the time of day
+func (p *Parallax) Render(queue *RenderQueue, camera *Camera, daylight float64, sky rl.Color) {
+	for i, layer := range p.Layers {
+		layer := layer
+		queue.Push(LayerSkyline, float32(i), func() {
+			p.drawLayer(layer, camera, layer.Tint(daylight, sky))
+		})
+	}
+}
+
+// Tint darkens the layer's color as the light fades, and fades it into the sky by its haze
+func (l *ParallaxLayer) Tint(daylight float64, sky rl.Color) rl.Color {
+	// Even at midnight the background doesn't go completely black
+	light := float32(0.3 + 0.7*daylight)
+	lit := rl.NewColor(uint8(float32(l.color.R)*light), uint8(float32(l.color.G)*light), uint8(float32(l.color.B)*light), l.color.A)
+	return lerpColor(lit, rl.NewColor(sky.R, sky.G, sky.B, l.color.A), l.Haze)
+}
+
+// Offset returns how far the layer has scrolled along one period, for the camera's position and how long it's drifted
+func (l *ParallaxLayer) Offset(cameraX float32, elapsed float64) float32 {
+	scrolled := float64(cameraX*l.Rate) + float64(l.Drift)*elapsed
+	return float32(math.Mod(math.Mod(scrolled, float64(l.Period))+float64(l.Period), float64(l.Period)))
+}
+
+// drawLayer draws as many periods of the layer as it takes to cover the screen
+func (p *Parallax) drawLayer(layer *ParallaxLayer, camera *Camera, color rl.Color) {
+	cameraX := float32(0)
+	if camera != nil {
+		cameraX = camera.X
+	}
+	bottom := float32(GroundLevel) - layer.Base
+	screenWidth := float32(rl.GetScreenWidth())
+	for start := -layer.Offset(cameraX, Input.Now().Seconds()); start < screenWidth; start += layer.Period {
+		for _, piece := range layer.pieces {
+			x := start + piece.Area.X
+			if x+piece.Area.Width < 0 || x > screenWidth {
+				continue
+			}
+			y := bottom - piece.Area.Height
+			switch layer.Kind {
+			case "towers":
+				rl.DrawRectangleRec(rl.NewRectangle(x, y, piece.Area.Width, piece.Area.Height), color)
+				for _, step := range piece.Steps {
+					rl.DrawRectangleRec(rl.NewRectangle(start+step.X, bottom-step.Y-step.Height, step.Width, step.Height), color)
+				}
+			case "clouds":
+				rl.DrawEllipse(int32(x+piece.Area.Width/2), int32(y+piece.Area.Height/2), piece.Area.Width/2, piece.Area.Height/2, color)
+			case "rubble":
+				if p.Palette != nil {
+					p.Palette.DrawTinted(piece.Brush, int(x), int(y), color)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallaxLayerGenerate(t *testing.T) {
+	layer := func() *ParallaxLayer {
+		return &ParallaxLayer{Kind: "towers", Color: "#404050", MinWidth: 20, MaxWidth: 40, MinHeight: 50, MaxHeight: 100, Gap: 10, Period: 500}
+	}
+	first, second := layer(), layer()
+	assert.NoError(t, first.Generate(rand.New(rand.NewSource(7))))
+	assert.NoError(t, second.Generate(rand.New(rand.NewSource(7))))
+	// The same seed builds the same skyline
+	assert.Equal(t, first.pieces, second.pieces)
+	assert.NotEmpty(t, first.pieces)
+	for _, piece := range first.pieces {
+		assert.True(t, piece.Area.X < first.Period)
+		assert.True(t, piece.Area.Width >= 20 && piece.Area.Width <= 40)
+	}
+
+	assert.Error(t, (&ParallaxLayer{Kind: "rubble", Color: "#ffffff", MaxWidth: 16, Period: 100}).Generate(rand.New(rand.NewSource(1))))
+	assert.Error(t, (&ParallaxLayer{Kind: "towers", Color: "nope", MaxWidth: 16, Period: 100}).Generate(rand.New(rand.NewSource(1))))
+}
+
+func TestParallaxLayerScrolling(t *testing.T) {
+	layer := &ParallaxLayer{Rate: 0.5, Drift: 10, Period: 100}
+	assert.Equal(t, float32(0), layer.Offset(0, 0))
+	assert.Equal(t, float32(50), layer.Offset(100, 0))
+	// Offsets wrap around the period, both ways
+	assert.Equal(t, float32(20), layer.Offset(200, 2))
+	assert.Equal(t, float32(75), layer.Offset(-50, 0))
+}
+
+func TestParallaxLayerTint(t *testing.T) {
+	layer := &ParallaxLayer{color: rl.NewColor(100, 100, 100, 255)}
+	sky := rl.NewColor(200, 220, 255, 255)
+	assert.Equal(t, rl.NewColor(100, 100, 100, 255), layer.Tint(1, sky))
+	assert.Equal(t, rl.NewColor(30, 30, 30, 255), layer.Tint(0, sky))
+
+	// A hazy layer fades into the sky
+	layer.Haze = 1
+	assert.Equal(t, sky, layer.Tint(1, sky))
+}
+
+func TestLoadParallax(t *testing.T) {
+	parallax, err := LoadParallax("assets/backgrounds/parallax.json", nil, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, parallax.Layers)
+	for _, layer := range parallax.Layers {
+		assert.NotEmpty(t, layer.pieces, layer.Name)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math/rand"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Range is an inclusive range that particle properties are rolled from
+type Range struct {
+	Min, Max float32
+}
+
+// Roll returns a value within the range, given a random number from 0 to 1
+func (r Range) Roll(random float32) float32 {
+	return r.Min + random*(r.Max-r.Min)
+}
+
+// Particle is a single particle owned by an Emitter
+type Particle struct {
+	Age, Lifetime int
+	X, Y          float32
+	VX, VY        float32
+}
+
+// Emitter spawns, moves, fades and cleans up particles. Particles are pooled, so once an emitter has warmed up it stops
+// allocating: dead particles are swapped to the end of the pool and reused by the next spawn
+type Emitter struct {
+	// Area is the rectangle that new particles spawn within
+	Area rl.Rectangle
+	// Bounds kills off any particle that leaves it. Leave it empty to only kill particles when they reach the end of their life
+	Bounds rl.Rectangle
+	// ColorStart fades to ColorEnd over the particle's lifetime
+	ColorStart, ColorEnd rl.Color
+	Done                 bool
+	// Gravity is added to a particle's Y velocity every update
+	Gravity  float32
+	Lifetime Range
+	// Live is how many particles at the front of the pool are alive
+	Live      int
+	Particles []Particle
+	// RNG is the random stream the emitter rolls particles from. Without one it falls back to the default particle stream
+	RNG *rand.Rand
+	// Rate is the average number of particles spawned each update
+	Rate float64
+	// SizeStart grows or shrinks to SizeEnd over the particle's lifetime
+	SizeStart, SizeEnd float32
+	// VelocityX and VelocityY are the ranges a new particle's speed is rolled from
+	VelocityX, VelocityY Range
+}
+
+// CanReap returns Emitter.Done
+func (e *Emitter) CanReap() bool {
+	return e.Done
+}
+
+// Draw renders each live particle, blending its color and size by age
+func (e *Emitter) Draw() {
+	for i := 0; i < e.Live; i++ {
+		p := &e.Particles[i]
+		age := float32(p.Age) / float32(p.Lifetime)
+		size := e.SizeStart + (e.SizeEnd-e.SizeStart)*age
+		rl.DrawRectangleRec(rl.NewRectangle(p.X, p.Y, size, size), lerpColor(e.ColorStart, e.ColorEnd, age))
+	}
+}
+
+// Update moves every live particle, kills off any that have expired or left the bounds, then spawns new particles at Rate
+func (e *Emitter) Update() {
+	bounded := e.Bounds.Width > 0 && e.Bounds.Height > 0
+	for i := 0; i < e.Live; {
+		p := &e.Particles[i]
+		p.VY += e.Gravity
+		p.X += p.VX
+		p.Y += p.VY
+		p.Age++
+		outside := p.X < e.Bounds.X || p.X > e.Bounds.X+e.Bounds.Width || p.Y < e.Bounds.Y || p.Y > e.Bounds.Y+e.Bounds.Height
+		if p.Age >= p.Lifetime || (bounded && outside) {
+			// Swap the dead particle out for the last live one, and check that one next
+			e.Live--
+			e.Particles[i] = e.Particles[e.Live]
+			continue
+		}
+		i++
+	}
+
+	// Spawn the whole part of our rate, and roll for the remainder
+	spawns := int(e.Rate)
+	if float64(e.random()) < e.Rate-float64(spawns) {
+		spawns++
+	}
+	e.Burst(spawns)
+}
+
+// Burst spawns the given number of particles at once
+func (e *Emitter) Burst(count int) {
+	for i := 0; i < count; i++ {
+		p := Particle{
+			Lifetime: int(e.Lifetime.Roll(e.random())),
+			X:        e.Area.X + e.random()*e.Area.Width,
+			Y:        e.Area.Y + e.random()*e.Area.Height,
+			VX:       e.VelocityX.Roll(e.random()),
+			VY:       e.VelocityY.Roll(e.random()),
+		}
+		if p.Lifetime < 1 {
+			p.Lifetime = 1
+		}
+		if e.Live < len(e.Particles) {
+			e.Particles[e.Live] = p
+		} else {
+			e.Particles = append(e.Particles, p)
+		}
+		e.Live++
+	}
+}
+
+// random returns a random number from 0 to 1 from the emitter's stream
+func (e *Emitter) random() float32 {
+	if e.RNG == nil {
+		return defaultRNG.Stream(StreamParticles).Float32()
+	}
+	return e.RNG.Float32()
+}
+
+// GetHitbox returns the area particles spawn in
+func (e *Emitter) GetHitbox() rl.Rectangle {
+	return e.Area
+}
+
+// lerpColor blends from one color to another, where amount is 0 for from and 1 for to
+func lerpColor(from, to rl.Color, amount float32) rl.Color {
+	return rl.NewColor(
+		uint8(float32(from.R)+(float32(to.R)-float32(from.R))*amount),
+		uint8(float32(from.G)+(float32(to.G)-float32(from.G))*amount),
+		uint8(float32(from.B)+(float32(to.B)-float32(from.B))*amount),
+		uint8(float32(from.A)+(float32(to.A)-float32(from.A))*amount),
+	)
+}
+
+// NewSmoke returns an emitter that puffs smoke up out of a chimney at the given point
+func NewSmoke(x, y float32) *Emitter {
+	return &Emitter{
+		Area:       rl.NewRectangle(x, y, 4, 2),
+		ColorStart: rl.NewColor(120, 120, 120, 160),
+		ColorEnd:   rl.NewColor(200, 200, 200, 0),
+		Lifetime:   Range{120, 200},
+		Rate:       0.1,
+		SizeStart:  3,
+		SizeEnd:    9,
+		VelocityX:  Range{0.05, 0.3},
+		VelocityY:  Range{-0.6, -0.3},
+	}
+}
+
+// NewFlames returns an emitter that licks flames up from the given area
+func NewFlames(area rl.Rectangle) *Emitter {
+	return &Emitter{
+		Area:       area,
+		ColorStart: rl.NewColor(255, 220, 60, 230),
+		ColorEnd:   rl.NewColor(200, 30, 0, 0),
+		Lifetime:   Range{20, 45},
+		Rate:       2,
+		SizeStart:  6,
+		SizeEnd:    2,
+		VelocityX:  Range{-0.3, 0.3},
+		VelocityY:  Range{-2, -0.8},
+	}
+}
+
+// NewSparkles returns an emitter for the glint of a coin as it lands. It doesn't spawn on its own, so call Burst
+func NewSparkles(x, y float32) *Emitter {
+	return &Emitter{
+		Area:       rl.NewRectangle(x, y, 16, 4),
+		ColorStart: rl.NewColor(255, 240, 120, 255),
+		ColorEnd:   rl.NewColor(255, 200, 40, 0),
+		Gravity:    0.15,
+		Lifetime:   Range{15, 30},
+		SizeStart:  3,
+		SizeEnd:    1,
+		VelocityX:  Range{-1.5, 1.5},
+		VelocityY:  Range{-3, -1},
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmitterLifetime(t *testing.T) {
+	emitter := &Emitter{Lifetime: Range{10, 10}, VelocityY: Range{1, 1}}
+	emitter.Burst(5)
+	assert.Equal(t, 5, emitter.Live)
+
+	for i := 0; i < 9; i++ {
+		emitter.Update()
+	}
+	assert.Equal(t, 5, emitter.Live)
+	assert.Equal(t, float32(9), emitter.Particles[0].Y)
+
+	emitter.Update()
+	assert.Equal(t, 0, emitter.Live)
+}
+
+func TestEmitterBounds(t *testing.T) {
+	emitter := &Emitter{Bounds: rl.NewRectangle(0, 0, 100, 10), Lifetime: Range{100, 100}, VelocityY: Range{2, 2}}
+	emitter.Burst(1)
+	for i := 0; i < 5; i++ {
+		emitter.Update()
+	}
+	assert.Equal(t, 1, emitter.Live)
+	emitter.Update()
+	assert.Equal(t, 0, emitter.Live)
+}
+
+func TestEmitterReusesPool(t *testing.T) {
+	emitter := &Emitter{Lifetime: Range{20, 20}, Rate: 3}
+	for i := 0; i < 100; i++ {
+		emitter.Update()
+	}
+	pool := len(emitter.Particles)
+	for i := 0; i < 1000; i++ {
+		emitter.Update()
+	}
+	// A steady rate and lifetime should settle into a pool that doesn't keep growing
+	assert.LessOrEqual(t, len(emitter.Particles), pool+3)
+	assert.LessOrEqual(t, emitter.Live, 20*3)
+}
+
+// legacyDroplet is how rain was simulated before the emitter, kept around to benchmark against
+type legacyDroplet struct {
+	Speed      int
+	XPos, YPos int
+}
+
+// legacyRainUpdate mirrors the original Rain.Update, deleting droplets out of the middle of the slice
+func legacyRainUpdate(droplets []*legacyDroplet, density, width, height int) []*legacyDroplet {
+	for i, droplet := range droplets {
+		droplet.YPos += droplet.Speed
+		if droplet.YPos > height {
+			if i == 0 {
+				droplets = droplets[1:]
+				continue
+			}
+			droplets = append(droplets[0:i], droplets[i+1:]...)
+		}
+	}
+	for i := 0; i < density; i++ {
+		droplets = append(droplets, &legacyDroplet{Speed: 6 + rand.Intn(10), XPos: rand.Intn(width), YPos: -4})
+	}
+	return droplets
+}
+
+func BenchmarkLegacyRain(b *testing.B) {
+	droplets := []*legacyDroplet{}
+	for i := 0; i < b.N; i++ {
+		droplets = legacyRainUpdate(droplets, 4, 1920, 1080)
+	}
+}
+
+func BenchmarkEmitterRain(b *testing.B) {
+	emitter := &Emitter{
+		Area:      rl.NewRectangle(0, -4, 1920, 1),
+		Bounds:    rl.NewRectangle(0, -8, 1920, 1088),
+		Lifetime:  Range{1080, 1080},
+		Rate:      4,
+		VelocityY: Range{6, 16},
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		emitter.Update()
+	}
+}
@@ -1,13 +1,12 @@
 package main
 
 import (
-	"math/rand"
-
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 // Person is an abstration for a person in the city
 type Person struct {
+	Identity
 	Counter  float32
 	Deceased bool
 	Dragged  bool
@@ -16,7 +15,9 @@ type Person struct {
 	Engine   *Engine
 	// Moving flags to animate the sprite
 	OnTask bool
-	Sprite Sprite
+	// Sheltered is true while the person is waiting out bad weather indoors
+	Sheltered bool
+	Sprite    Sprite
 	// Step tracks the animation
 	Step int
 	// 0 - clicked
@@ -29,6 +30,11 @@ func (person *Person) CanReap() bool {
 	return person.Deceased
 }
 
+// Category files the person under people
+func (person *Person) Category() Category {
+	return CategoryPerson
+}
+
 // IsFalling is a simple helper to stop other animations when falling
 func (person *Person) IsFalling() bool {
 	return int(person.Sprite.LevelY) < GroundLevel
@@ -41,14 +47,37 @@ func (person *Person) IsFalling() bool {
 func (person *Person) Init(engine *Engine) {
 	person.Engine = engine
 	person.Sounds = make(map[int]rl.Sound)
-	person.Sounds[0] = rl.LoadSound("assets/sounds/jump.mp3")
-	person.Sounds[1] = rl.LoadSound("assets/sounds/arrived.mp3")
+	for i, path := range personSounds {
+		person.Sounds[i] = Assets.Sound(path)
+	}
 	rl.PlaySound(person.Sounds[1])
+	// Everyone heads indoors when the weather turns, whatever else they're up to. It goes first, so the waypoint it
+	// sets is the one they walk to
+	person.Effects = append([]func(*Person){SeekShelter}, person.Effects...)
+}
+
+// personSounds are the sounds a person loads, by their key in Person.Sounds
+var personSounds = []string{"assets/sounds/jump.mp3", "assets/sounds/arrived.mp3"}
+
+// Release gives the person's sprite and sounds back to the asset manager
+func (person *Person) Release() {
+	person.Sprite.Release()
+	for i := range person.Sounds {
+		Assets.ReleaseSound(personSounds[i])
+	}
+}
+
+// Snapshot remembers where the person was before an update
+func (person *Person) Snapshot() {
+	person.Sprite.Snapshot()
 }
 
-// Draw renders a person's sprite to the screen
+// Draw renders a person's sprite to the screen, unless they're indoors
 func (person *Person) Draw() {
-	person.Sprite.Draw()
+	if person.Sheltered {
+		return
+	}
+	person.Sprite.Draw(person.Engine.Alpha())
 }
 
 // Update updates the sprites and runs any effects (like Person wandering etc)
@@ -72,15 +101,15 @@ func (person *Person) Update() {
 		person.OnTask = false
 		person.Counter = 0
 
-		person.Sprite.LevelX = float32(rl.GetMouseX())
-		if int(rl.GetMouseY()) <= GroundLevel {
-			person.Sprite.LevelY = float32(rl.GetMouseY())
+		person.Sprite.LevelX = person.Engine.Camera.WorldX(Input.MouseX())
+		if int(Input.MouseY()) <= GroundLevel {
+			person.Sprite.LevelY = float32(Input.MouseY())
 		} else {
 			person.Sprite.LevelY = float32(GroundLevel)
 		}
 		rl.PlaySound(person.Sounds[0])
 
-		if rl.IsMouseButtonDown(rl.MouseRightButton) || rl.IsMouseButtonUp(rl.MouseLeftButton) {
+		if Input.MouseDown(rl.MouseRightButton) || !Input.MouseDown(rl.MouseLeftButton) {
 			person.Dragged = !person.Dragged
 		}
 	} else {
@@ -92,10 +121,24 @@ func (person *Person) Update() {
 			}
 			person.Counter++
 		}
-		if !person.IsFalling() {
-			person.Sprite.Update()
-		}
 	}
+	person.Sprite.Play(person.Clip())
+	person.Sprite.Update()
+}
+
+// Clip returns the name of the animation for whatever the person is doing
+func (person *Person) Clip() string {
+	switch {
+	case person.Sheltered:
+		return "sleep"
+	case person.Dragged:
+		return "carried"
+	case person.IsFalling():
+		return "fall"
+	case person.OnTask && person.Sprite.LevelX != person.WaypointX:
+		return "walk"
+	}
+	return "idle"
 }
 
 // GetHitbox returns a rectangle to represent the entity hitbox
@@ -103,19 +146,29 @@ func (person *Person) GetHitbox() rl.Rectangle {
 	return rl.NewRectangle(person.Sprite.LevelX, person.Sprite.LevelY, person.Sprite.Width, person.Sprite.Height)
 }
 
-// IsClicked returns true when a person is clicked on
+// IsClicked returns true when a person is clicked on. Only the person in front gets picked up when they overlap
 func (person *Person) IsClicked() bool {
-	return rl.IsMouseButtonDown(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.Vector2{X: float32(rl.GetMouseX()), Y: float32(rl.GetMouseY())}, person.GetHitbox())
+	if person.Sheltered || !Input.MouseDown(rl.MouseLeftButton) {
+		return false
+	}
+	x, y := person.Engine.Camera.WorldX(Input.MouseX()), float32(Input.MouseY())
+	if !rl.CheckCollisionPointRec(rl.Vector2{X: x, Y: y}, person.GetHitbox()) {
+		return false
+	}
+	return person.Engine.Pick(CategoryPerson, x, y) == Entity(person)
 }
 
 // Wander is an effect intended to set a waypoint for a Person, then walk them to it.
 func Wander(person *Person) {
-	// rate 60 is roughly once a second
-	rate := 60 * 5
+	// rate TickRate is once a second
+	rate := TickRate * 5
+	if person.Sheltered {
+		return
+	}
 	if !person.OnTask && !person.IsFalling() {
-		person.Sprite.Animated = false
-		if rand.Intn(rate) == 1 {
-			waypoint := rand.Intn(rl.GetScreenWidth())
+		rng := person.Engine.Rand(StreamPeople)
+		if rng.Intn(rate) == 1 {
+			waypoint := rng.Intn(WorldWidth)
 			remainder := waypoint % 4
 			person.OnTask = true
 			person.WaypointX = float32(waypoint - remainder)
@@ -125,12 +178,10 @@ func Wander(person *Person) {
 		}
 	} else {
 		if person.Sprite.LevelX > person.WaypointX {
-			person.Sprite.Animated = true
 			person.Sprite.Reversed = true
 			person.Sprite.LevelX -= float32(person.Sprite.Speed)
 		}
 		if person.Sprite.LevelX < person.WaypointX {
-			person.Sprite.Animated = true
 			person.Sprite.Reversed = false
 			person.Sprite.LevelX += float32(person.Sprite.Speed)
 		}
@@ -143,12 +194,12 @@ func Wander(person *Person) {
 
 // MoneyBags is a modifier that makes the person its effecting drop their dosh
 func MoneyBags(person *Person) {
-	// rate 60 * 60 is roughly once a minute
-	rate := 60 * 60
-	if person.Dosh > 0 && rand.Intn(rate) == 1 {
+	// rate TickRate * 60 is once a minute
+	rate := TickRate * 60
+	if person.Dosh > 0 && person.Engine.Rand(StreamPeople).Intn(rate) == 1 {
 		// DropRate means we will drop X times where X=dropRate
 		dropRate := 10
 		coin := NewCoin(person.Engine, float64(person.Dosh/dropRate), person.Sprite.LevelX, person.Sprite.LevelY)
-		person.Engine.Entities = append(person.Engine.Entities, coin)
+		person.Engine.Spawn(coin)
 	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// NewImagePalette is NewSpacedPalette without raylib. The spritesheet is decoded into memory instead of uploaded as a
+// texture, for drawing stamps with Rasterize where there's no window, like in tests
+func NewImagePalette(filepath string, tileHeight, tileWidth, margin, spacing int) (*Palette, *image.NRGBA, error) {
+	palette := &Palette{Spritesheet: filepath, TileHeight: tileHeight, TileWidth: tileWidth, Margin: margin, Spacing: spacing}
+	sheet, err := palette.Image()
+	if err != nil {
+		return nil, nil, err
+	}
+	palette.Width, palette.Height = sheet.Rect.Dx(), sheet.Rect.Dy()
+	palette.Brushes = BrushMap(palette.Width, palette.Height, tileWidth, tileHeight, margin, spacing)
+	return palette, sheet, nil
+}
+
+// Image decodes the palette's spritesheet from the assets. A variant is decoded from the spritesheet it was recolored
+// from, and recolored the same way
+func (p *Palette) Image() (*image.NRGBA, error) {
+	source := p.Spritesheet
+	if p.Base != "" {
+		source = p.Base
+	}
+	data, err := ReadAsset(source)
+	if err != nil {
+		return nil, err
+	}
+	width, height, pixels, err := decodeColors(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", source, err)
+	}
+	p.Recolor.Apply(pixels)
+
+	sheet := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, pixel := range pixels {
+		copy(sheet.Pix[i*4:], []uint8{pixel.R, pixel.G, pixel.B, pixel.A})
+	}
+	return sheet, nil
+}
+
+// Rasterize draws the stamp into an image the size of the stamp, using the palette's brushes cut from the sheet.
+// Brushes are laid over each other in order, blended the way raylib blends them on screen
+func (p *Palette) Rasterize(stamp *Stamp, sheet image.Image) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, int(stamp.Width), int(stamp.Height)))
+	for _, coord := range stamp.DrawCoords {
+		brush, ok := p.Brushes[coord.Brush]
+		if !ok {
+			return nil, fmt.Errorf("brush %v isn't in the palette", coord.Brush)
+		}
+		at := image.Pt(int(coord.XOffset), int(coord.YOffset))
+		area := image.Rectangle{Min: at, Max: at.Add(image.Pt(int(brush.Width), int(brush.Height)))}
+		draw.Draw(img, area, sheet, image.Pt(int(brush.XPos), int(brush.YPos)), draw.Over)
+	}
+	return img, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// updateGolden rewrites the golden images from what's drawn now: go test -run Golden -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+func TestRasterize(t *testing.T) {
+	// A 2x1 tilesheet of 2 pixel tiles: a red one, and a blue one with a transparent pixel
+	sheet := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		sheet.SetNRGBA(0, y, color.NRGBA{255, 0, 0, 255})
+		sheet.SetNRGBA(1, y, color.NRGBA{255, 0, 0, 255})
+		sheet.SetNRGBA(2, y, color.NRGBA{0, 0, 255, 255})
+		sheet.SetNRGBA(3, y, color.NRGBA{0, 0, 255, 255})
+	}
+	sheet.SetNRGBA(2, 0, color.NRGBA{})
+	palette := &Palette{Brushes: BrushMap(4, 2, 2, 2, 0, 0)}
+
+	stamp := &Stamp{Width: 4, Height: 2, DrawCoords: []DrawCoord{{0, 0, 0}, {1, 0, 0}, {1, 2, 0}}}
+	img, err := palette.Rasterize(stamp, sheet)
+	assert.NoError(t, err)
+	// The blue brush covers the red one, except where it's transparent
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, img.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{0, 0, 255, 255}, img.RGBAAt(1, 0))
+	assert.Equal(t, color.RGBA{}, img.RGBAAt(2, 0))
+	assert.Equal(t, color.RGBA{0, 0, 255, 255}, img.RGBAAt(3, 1))
+
+	stamp.DrawCoords = append(stamp.DrawCoords, DrawCoord{9, 0, 0})
+	_, err = palette.Rasterize(stamp, sheet)
+	assert.Error(t, err)
+}
+
+// TestCatalogGolden draws every building in the catalog and compares it against its image in testdata, so changes to
+// the tileset or the Tiled parser show up as a changed picture
+func TestCatalogGolden(t *testing.T) {
+	palette, sheet, err := NewImagePalette("assets/sprites/projectmute.png", 16, 16, 0, 0)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for name := range Catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		engine := &Engine{RNG: NewRNG(1)}
+		building := Catalog[name](engine, palette)
+		img, err := palette.Rasterize(building.Stamp, sheet)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		assertGolden(t, filepath.Join("testdata", "buildings", name+".png"), img)
+	}
+}
+
+// assertGolden compares the image against the golden PNG at the path. When they differ, what was drawn is saved to
+// the temp directory to compare by eye
+func assertGolden(t *testing.T, path string, img *image.RGBA) {
+	t.Helper()
+	if *updateGolden {
+		assert.NoError(t, SaveScreenshot(img, path))
+		return
+	}
+
+	file, err := os.Open(path)
+	if !assert.NoError(t, err, "run with -update to create it") {
+		return
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if !assert.NoError(t, err, path) {
+		return
+	}
+
+	if diff := imageDiff(decoded, img); diff != "" {
+		actual := filepath.Join(os.TempDir(), "pixelopolis-golden", filepath.Base(path))
+		if err := SaveScreenshot(img, actual); err == nil {
+			diff += fmt.Sprintf(", drawn to %v", actual)
+		}
+		t.Errorf("%v differs from its golden image: %v", path, diff)
+	}
+}
+
+// imageDiff describes how the images differ, or returns nothing if they're the same
+func imageDiff(want, got image.Image) string {
+	if want.Bounds() != got.Bounds() {
+		return fmt.Sprintf("size is %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	count := 0
+	first := image.Point{}
+	bounds := want.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if color.NRGBAModel.Convert(want.At(x, y)) != color.NRGBAModel.Convert(got.At(x, y)) {
+				if count == 0 {
+					first = image.Pt(x, y)
+				}
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v pixels differ, starting at %v", count, first)
+}
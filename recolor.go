@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ColorRamp is a run of shades from darkest to lightest, like the greys a tileset is drawn in
+type ColorRamp []rl.Color
+
+// MuteRamp is the greys the projectmute tileset is drawn in
+var MuteRamp = ColorRamp{
+	rl.NewColor(85, 87, 99, 255),
+	rl.NewColor(117, 121, 138, 255),
+	rl.NewColor(139, 142, 158, 255),
+	rl.NewColor(155, 159, 177, 255),
+}
+
+// PreviewRamps are the shades the building preview is tinted with: green when it can be built, yellow when it's in the
+// way of another building, and red when it can't be afforded
+var PreviewRamps = map[string]ColorRamp{
+	"green": {
+		rl.NewColor(71, 113, 53, 255),
+		rl.NewColor(98, 157, 73, 255),
+		rl.NewColor(121, 175, 98, 255),
+		rl.NewColor(134, 198, 106, 255),
+	},
+	"yellow": {
+		rl.NewColor(131, 106, 53, 255),
+		rl.NewColor(182, 148, 73, 255),
+		rl.NewColor(198, 164, 98, 255),
+		rl.NewColor(226, 186, 106, 255),
+	},
+	"red": {
+		rl.NewColor(131, 81, 104, 255),
+		rl.NewColor(182, 112, 144, 255),
+		rl.NewColor(198, 134, 164, 255),
+		rl.NewColor(226, 149, 185, 255),
+	},
+}
+
+// ParseColorRamp parses a ramp written as hex colors, like "#555763"
+func ParseColorRamp(colors []string) (ColorRamp, error) {
+	ramp := ColorRamp{}
+	for _, c := range colors {
+		rgb, err := hex.DecodeString(strings.TrimPrefix(c, "#"))
+		if err != nil || len(rgb) != 3 {
+			return nil, fmt.Errorf("%q isn't a color like #555763", c)
+		}
+		ramp = append(ramp, rl.NewColor(rgb[0], rgb[1], rgb[2], 255))
+	}
+	return ramp, nil
+}
+
+// RampSwap swaps every shade of one ramp for the shade at the same step along another. The ramps don't have to be the
+// same length
+type RampSwap struct {
+	From, To ColorRamp
+}
+
+// Recolor is a set of ramp swaps, applied together to recolor a spritesheet
+type Recolor []RampSwap
+
+// Apply recolors the pixels in place. Each pixel keeps its alpha, and anything not on one of the ramps is left alone
+func (r Recolor) Apply(pixels []rl.Color) {
+	type rgb struct {
+		r, g, b uint8
+	}
+	swaps := make(map[rgb]rl.Color)
+	for _, swap := range r {
+		if len(swap.To) == 0 {
+			continue
+		}
+		for i, from := range swap.From {
+			swaps[rgb{from.R, from.G, from.B}] = swap.To[i*len(swap.To)/len(swap.From)]
+		}
+	}
+
+	for i, pixel := range pixels {
+		if to, ok := swaps[rgb{pixel.R, pixel.G, pixel.B}]; ok {
+			pixels[i] = rl.NewColor(to.R, to.G, to.B, pixel.A)
+		}
+	}
+}
+
+// Variant returns a copy of the palette with its spritesheet recolored, cut into the same brushes
+func (p *Palette) Variant(name string, recolor Recolor) *Palette {
+	texture, key := Assets.RecoloredTexture(p.Spritesheet, name, recolor)
+	return &Palette{
+		Base:        p.Spritesheet,
+		Brushes:     p.Brushes,
+		Recolor:     recolor,
+		Spritesheet: key,
+		Texture:     texture,
+		Width:       int(texture.Width),
+		Height:      int(texture.Height),
+		TileHeight:  p.TileHeight,
+		TileWidth:   p.TileWidth,
+		Margin:      p.Margin,
+		Spacing:     p.Spacing,
+	}
+}
+
+// AddPreviewVariants recolors the palette into each of the preview ramps, from the ramp its spritesheet is drawn in
+func (p *Palette) AddPreviewVariants(ramp ColorRamp) {
+	if p.Variants == nil {
+		p.Variants = make(map[string]*Palette)
+	}
+	for name, preview := range PreviewRamps {
+		p.Variants[name] = p.Variant(name, Recolor{{From: ramp, To: preview}})
+	}
+}
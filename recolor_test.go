@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecolor(t *testing.T) {
+	outline := rl.NewColor(0, 0, 0, 255)
+	faded := MuteRamp[1]
+	faded.A = 1
+	pixels := []rl.Color{MuteRamp[0], MuteRamp[3], outline, faded}
+
+	Recolor{{From: MuteRamp, To: PreviewRamps["red"]}}.Apply(pixels)
+	assert.Equal(t, PreviewRamps["red"][0], pixels[0])
+	assert.Equal(t, PreviewRamps["red"][3], pixels[1])
+	// Anything off the ramp is left alone, and alpha is kept
+	assert.Equal(t, outline, pixels[2])
+	assert.Equal(t, rl.NewColor(182, 112, 144, 1), pixels[3])
+
+	// A longer ramp is stepped down onto a shorter one
+	pixels = []rl.Color{MuteRamp[0], MuteRamp[1], MuteRamp[2], MuteRamp[3]}
+	black, white := rl.NewColor(0, 0, 0, 255), rl.NewColor(255, 255, 255, 255)
+	Recolor{{From: MuteRamp, To: ColorRamp{black, white}}}.Apply(pixels)
+	assert.Equal(t, []rl.Color{black, black, white, white}, pixels)
+}
+
+func TestParseColorRamp(t *testing.T) {
+	ramp, err := ParseColorRamp([]string{"#555763", "75798a"})
+	assert.NoError(t, err)
+	assert.Equal(t, ColorRamp{MuteRamp[0], MuteRamp[1]}, ramp)
+
+	_, err = ParseColorRamp([]string{"#5557"})
+	assert.Error(t, err)
+}
+
+func TestRecoloredTexture(t *testing.T) {
+	assets, _, unloads := stubAssets()
+	generated := 0
+	assets.loadPixels = func(width, height int, pixels []rl.Color) rl.Texture2D {
+		generated++
+		assert.Len(t, pixels, width*height)
+		return rl.Texture2D{ID: 99, Width: int32(width), Height: int32(height)}
+	}
+
+	recolor := Recolor{{From: MuteRamp, To: PreviewRamps["green"]}}
+	first, key := assets.RecoloredTexture("assets/sprites/projectmute.png", "green", recolor)
+	second, _ := assets.RecoloredTexture("assets/sprites/projectmute.png", "green", recolor)
+	assert.Equal(t, "assets/sprites/projectmute.png#green", key)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, generated)
+	assert.Equal(t, int32(256), first.Width)
+
+	assets.ReleaseTexture(key)
+	assets.ReleaseTexture(key)
+	assert.Equal(t, 0, assets.Report().Textures)
+	assert.Len(t, unloads, 1)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// RenderLayer is a named depth in the scene. Layers are drawn in order, so each one covers everything in the layers before it
+type RenderLayer int
+
+const (
+	LayerSky RenderLayer = iota
+	// LayerSkyline is the parallax background, which scrolls at its own rates
+	LayerSkyline
+	LayerGround
+	LayerBuildings
+	LayerDecorations
+	LayerPeople
+	LayerVehicles
+	LayerParticles
+	// LayerWeather covers the whole street, like rain, fog and floods
+	LayerWeather
+	// LayerLighting darkens the city at night, and lights up its windows and lamps
+	LayerLighting
+	LayerUI
+)
+
+// worldLayers are drawn on the street, scrolled by the camera. The rest are drawn straight onto the screen
+var worldLayers = map[RenderLayer]bool{
+	LayerGround:      true,
+	LayerBuildings:   true,
+	LayerDecorations: true,
+	LayerPeople:      true,
+	LayerVehicles:    true,
+	LayerParticles:   true,
+	LayerLighting:    true,
+}
+
+// categoryLayers are the layers entities draw to, by category, unless they're a Renderer and pick their own
+var categoryLayers = map[Category]RenderLayer{
+	CategoryBuilding: LayerBuildings,
+	CategoryEffect:   LayerParticles,
+	CategoryPerson:   LayerPeople,
+	CategoryVehicle:  LayerVehicles,
+}
+
+// Renderer is an entity that queues its own draws, usually because it has parts that go on different layers
+type Renderer interface {
+	Render(queue *RenderQueue)
+}
+
+// drawCommand is a draw waiting in the queue
+type drawCommand struct {
+	draw  func()
+	layer RenderLayer
+	// order is when the draw was queued, to keep ties in the order they came in
+	order int
+	z     float32
+}
+
+// RenderQueue collects a frame's draws from everywhere, then draws them back to front: by layer, then by Z within a
+// layer, then in the order they were queued
+type RenderQueue struct {
+	// Camera scrolls the world layers into view. Without one they're drawn as they are
+	Camera   *Camera
+	commands []drawCommand
+}
+
+// Push queues a draw on the layer. Within a layer, lower Z is drawn first. Things standing on the street use the
+// bottom of their hitbox, so whatever's nearer the bottom of the screen is drawn in front
+func (q *RenderQueue) Push(layer RenderLayer, z float32, draw func()) {
+	q.commands = append(q.commands, drawCommand{draw: draw, layer: layer, order: len(q.commands), z: z})
+}
+
+// PushEntity queues the entity's draws. Renderers queue their own, and anything else is drawn whole on its category's
+// layer
+func (q *RenderQueue) PushEntity(entity Entity) {
+	if renderer, ok := entity.(Renderer); ok {
+		renderer.Render(q)
+		return
+	}
+	hitbox := entity.GetHitbox()
+	q.Push(categoryLayers[entity.Category()], hitbox.Y+hitbox.Height, entity.Draw)
+}
+
+// Len returns how many draws are queued
+func (q *RenderQueue) Len() int {
+	return len(q.commands)
+}
+
+// Flush draws everything queued, back to front, and empties the queue for the next frame
+func (q *RenderQueue) Flush() {
+	sort.Slice(q.commands, func(i, j int) bool {
+		a, b := q.commands[i], q.commands[j]
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		if a.z != b.z {
+			return a.z < b.z
+		}
+		return a.order < b.order
+	})
+	world := false
+	for _, command := range q.commands {
+		if q.Camera != nil && worldLayers[command.layer] != world {
+			world = !world
+			if world {
+				rl.BeginMode2D(q.Camera.Camera2D())
+			} else {
+				rl.EndMode2D()
+			}
+		}
+		command.draw()
+	}
+	if world {
+		rl.EndMode2D()
+	}
+	q.commands = q.commands[:0]
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderQueueOrder(t *testing.T) {
+	drawn := []string{}
+	draw := func(name string) func() {
+		return func() {
+			drawn = append(drawn, name)
+		}
+	}
+
+	queue := &RenderQueue{}
+	queue.Push(LayerUI, 0, draw("ui"))
+	queue.Push(LayerPeople, 300, draw("near person"))
+	queue.Push(LayerPeople, 200, draw("far person"))
+	queue.Push(LayerSky, 0, draw("sky"))
+	queue.Push(LayerBuildings, 300, draw("first building"))
+	queue.Push(LayerBuildings, 300, draw("second building"))
+	assert.Equal(t, 6, queue.Len())
+
+	queue.Flush()
+	assert.Equal(t, []string{"sky", "first building", "second building", "far person", "near person", "ui"}, drawn)
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestRenderEntities(t *testing.T) {
+	engine := &Engine{}
+	person := &Person{}
+	person.Sprite.Width, person.Sprite.Height = 32, 32
+	person.Sprite.LevelY = float32(GroundLevel)
+	engine.Add(person)
+	house := PlaceBuilding(engine, GetHouse(engine, nil), nil, 0)
+	house.Smoke = &Emitter{}
+	fire := &Fire{Building: house, Flames: &Emitter{}}
+	engine.Add(fire)
+
+	queue := &RenderQueue{}
+	engine.Render(queue, rl.NewRectangle(0, 0, 800, 800))
+	layers := []RenderLayer{}
+	for _, command := range queue.commands {
+		layers = append(layers, command.layer)
+	}
+	// The building queues its smoke separately, and the fire its glow and flames
+	assert.ElementsMatch(t, []RenderLayer{LayerBuildings, LayerParticles, LayerPeople, LayerDecorations, LayerParticles}, layers)
+}
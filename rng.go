@@ -0,0 +1,109 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// The random streams handed out to each subsystem. Keeping them apart means changes in one subsystem, like how many
+// droplets a storm spawns, don't change what happens in another, like which citizens get out of the taxi
+const (
+	StreamBuildings   = "buildings"
+	StreamCoins       = "coins"
+	StreamDecorations = "decorations"
+	StreamDisasters   = "disasters"
+	StreamParticles   = "particles"
+	StreamPeople      = "people"
+	StreamTaxi        = "taxi"
+	StreamTerrain     = "terrain"
+	StreamWeather     = "weather"
+)
+
+// RNG hands out independent random streams for each subsystem, all derived from a single seed.
+// The same seed always produces the same city
+type RNG struct {
+	Seed    int64
+	sources map[string]*countedSource
+	streams map[string]*rand.Rand
+}
+
+// NewRNG returns an RNG seeded with the given seed
+func NewRNG(seed int64) *RNG {
+	return &RNG{Seed: seed, sources: make(map[string]*countedSource), streams: make(map[string]*rand.Rand)}
+}
+
+// Stream returns the named random stream, creating it the first time it's asked for. A stream's seed comes from mixing the
+// RNG's seed with the stream's name, so it doesn't matter what order the streams are first used in
+func (r *RNG) Stream(name string) *rand.Rand {
+	if stream, ok := r.streams[name]; ok {
+		return stream
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	seed := int64(splitmix(uint64(r.Seed) ^ hash.Sum64()))
+	source := &countedSource{Source64: rand.NewSource(seed).(rand.Source64), seed: seed}
+	stream := rand.New(source)
+	r.sources[name] = source
+	r.streams[name] = stream
+	return stream
+}
+
+// Positions returns how many numbers each stream has handed out, to save with the city
+func (r *RNG) Positions() map[string]uint64 {
+	positions := make(map[string]uint64)
+	for name, source := range r.sources {
+		positions[name] = source.draws
+	}
+	return positions
+}
+
+// Seek moves each stream on to the position it was saved at, and any stream that wasn't saved back to its start.
+// Streams are moved in place, so anything already holding one carries on from the new position
+func (r *RNG) Seek(positions map[string]uint64) {
+	for name := range positions {
+		r.Stream(name)
+	}
+	for name, source := range r.sources {
+		source.seek(positions[name])
+	}
+}
+
+// countedSource counts the numbers drawn from a source, so a stream can be wound back to the same position later
+type countedSource struct {
+	rand.Source64
+	draws uint64
+	seed  int64
+}
+
+// Int63 draws the next number
+func (s *countedSource) Int63() int64 {
+	s.draws++
+	return s.Source64.Int63()
+}
+
+// Uint64 draws the next number
+func (s *countedSource) Uint64() uint64 {
+	s.draws++
+	return s.Source64.Uint64()
+}
+
+// seek reseeds the source and draws numbers until it's at the position
+func (s *countedSource) seek(draws uint64) {
+	s.Source64.Seed(s.seed)
+	for i := uint64(0); i < draws; i++ {
+		s.Source64.Uint64()
+	}
+	s.draws = draws
+}
+
+// splitmix scrambles the bits of a seed, so seeds that are close together don't produce streams that look alike
+func splitmix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// defaultRNG serves anything that asks for randomness without an engine, like previews
+var defaultRNG = NewRNG(0)
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRNGIsReproducible(t *testing.T) {
+	first, second := NewRNG(42), NewRNG(42)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first.Stream(StreamPeople).Int63(), second.Stream(StreamPeople).Int63())
+	}
+}
+
+func TestRNGStreamsAreIndependent(t *testing.T) {
+	quiet, noisy := NewRNG(42), NewRNG(42)
+	// Pulling from one stream mustn't change what another hands out
+	for i := 0; i < 1000; i++ {
+		noisy.Stream(StreamParticles).Float32()
+	}
+	assert.Equal(t, quiet.Stream(StreamTaxi).Int63(), noisy.Stream(StreamTaxi).Int63())
+	assert.NotEqual(t, quiet.Stream(StreamPeople).Int63(), quiet.Stream(StreamTaxi).Int63())
+}
+
+func TestTaxiLeavesPeopleAlone(t *testing.T) {
+	defer func(width int, assets *AssetManager) { WorldWidth, Assets = width, assets }(WorldWidth, Assets)
+	WorldWidth = 1000
+	Assets, _, _ = stubAssets()
+	Assets.loadSound = func(string) rl.Sound { return rl.Sound{} }
+	quiet, busy := &Engine{RNG: NewRNG(42)}, &Engine{RNG: NewRNG(42)}
+	// The taxi drops off its passengers and its fare halfway along the street
+	taxi := &Taxi{Engine: busy, Passengers: 2}
+	taxi.Sprite.LevelX = float32(WorldWidth/2) - 4
+	busy.Add(taxi)
+	for i := 0; i < 10 && len(busy.People()) == 0; i++ {
+		busy.Update()
+	}
+	assert.Len(t, busy.People(), 2)
+	assert.Len(t, busy.ByCategory(CategoryEffect), 1)
+	assert.Equal(t, quiet.Rand(StreamPeople).Int63(), busy.Rand(StreamPeople).Int63())
+}
+
+func TestRNGSeek(t *testing.T) {
+	played := NewRNG(42)
+	for i := 0; i < 100; i++ {
+		played.Stream(StreamPeople).Intn(10)
+		played.Stream(StreamWeather).Float64()
+	}
+	positions := played.Positions()
+
+	loaded := NewRNG(42)
+	// Streams already handed out are moved in place, and ones that weren't saved go back to the start
+	people := loaded.Stream(StreamPeople)
+	loaded.Stream(StreamTaxi).Int63()
+	loaded.Seek(positions)
+
+	assert.Equal(t, played.Stream(StreamPeople).Int63(), people.Int63())
+	assert.Equal(t, played.Stream(StreamWeather).Int63(), loaded.Stream(StreamWeather).Int63())
+	assert.Equal(t, played.Stream(StreamTaxi).Int63(), loaded.Stream(StreamTaxi).Int63())
+	assert.Equal(t, played.Positions(), loaded.Positions())
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.json")
+	engine := &Engine{Dosh: 123, DisasterFrequency: DisastersRare, RNG: NewRNG(7)}
+	engine.Calendar.DayLength, engine.Calendar.Ticks = 600, 500
+	PlaceBuilding(engine, GetHouse(engine, nil), nil, 32)
+	PlaceBuilding(engine, GetFireStation(engine, nil), nil, 128)
+	for i := 0; i < 50; i++ {
+		engine.Rand(StreamDisasters).Intn(100)
+	}
+	assert.NoError(t, engine.Save(path))
+
+	save, err := LoadCity(path)
+	assert.NoError(t, err)
+	loaded := &Engine{}
+	assert.NoError(t, save.Restore(loaded, nil))
+
+	assert.Equal(t, int64(7), loaded.RNG.Seed)
+	assert.Equal(t, 123.0, loaded.Dosh)
+	assert.Equal(t, 500, loaded.Calendar.Ticks)
+	assert.Equal(t, 600, loaded.Calendar.DayLength)
+
+	// A day length given on the command line is kept
+	configured := &Engine{Calendar: Calendar{DayLength: 900}}
+	assert.NoError(t, save.Restore(configured, nil))
+	assert.Equal(t, 900, configured.Calendar.DayLength)
+	assert.Equal(t, DisastersRare, loaded.DisasterFrequency)
+	assert.Equal(t, engine.PopulationMax, loaded.PopulationMax)
+	assert.Len(t, loaded.Buildings(), 2)
+	// The city carries on rolling the same numbers it would have without being saved
+	for _, stream := range []string{StreamDisasters, StreamDecorations, StreamBuildings} {
+		assert.Equal(t, engine.Rand(stream).Int63(), loaded.Rand(stream).Int63(), stream)
+	}
+}
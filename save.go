@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// SavedBuilding is a building as it's written to a save file
+type SavedBuilding struct {
+	Name string  `json:"name"`
+	X    float32 `json:"x"`
+}
+
+// CitySave is the data model for saving and loading a city. The seed and how far along each random stream was are saved
+// with the city, so loading it picks the streams back up where they left off
+type CitySave struct {
+	Buildings []SavedBuilding `json:"buildings"`
+	// DayLength is how many updates made up a day, so the saved ticks land on the same time of day
+	DayLength         int               `json:"dayLength,omitempty"`
+	DisasterFrequency DisasterFrequency `json:"disasterFrequency"`
+	Dosh              float64           `json:"dosh"`
+	Seed              int64             `json:"seed"`
+	// Streams are how many numbers each random stream had handed out
+	Streams map[string]uint64 `json:"streams,omitempty"`
+	Ticks   int               `json:"ticks"`
+}
+
+// Save writes the city out to the given filepath
+func (e *Engine) Save(filepath string) error {
+	save := CitySave{
+		DayLength:         e.Calendar.DayLength,
+		DisasterFrequency: e.DisasterFrequency,
+		Dosh:              e.Dosh,
+		Ticks:             e.Calendar.Ticks,
+	}
+	if e.RNG != nil {
+		save.Seed = e.RNG.Seed
+		save.Streams = e.RNG.Positions()
+	}
+	for _, building := range e.Buildings() {
+		save.Buildings = append(save.Buildings, SavedBuilding{Name: building.Name, X: building.Stamp.LevelX})
+	}
+
+	data, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath, data, 0644)
+}
+
+// LoadCity reads a save file
+func LoadCity(filepath string) (*CitySave, error) {
+	data, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return &CitySave{}, err
+	}
+
+	save := &CitySave{}
+	err = json.Unmarshal(data, save)
+	if err != nil {
+		return &CitySave{}, err
+	}
+	return save, nil
+}
+
+// Restore rebuilds the saved city in the engine, drawing the buildings with the given palette. The engine is reseeded
+// with the saved seed, unless it's already running from it, and once the buildings are up each stream is moved on to
+// where it was when the city was saved
+func (save *CitySave) Restore(engine *Engine, palette *Palette) error {
+	if engine.RNG == nil || engine.RNG.Seed != save.Seed {
+		engine.RNG = NewRNG(save.Seed)
+	}
+	// A day length the engine's already been given, like one on the command line, wins over the save's
+	if engine.Calendar.DayLength == 0 {
+		engine.Calendar.DayLength = save.DayLength
+	}
+	engine.Calendar.Ticks = save.Ticks
+	engine.DisasterFrequency = save.DisasterFrequency
+	engine.Dosh = save.Dosh
+
+	for _, saved := range save.Buildings {
+		build, ok := Catalog[saved.Name]
+		if !ok {
+			return fmt.Errorf("unknown building %q in save", saved.Name)
+		}
+		PlaceBuilding(engine, build(engine, palette), palette, saved.X)
+	}
+	// Saves from before stream positions were kept carry on from wherever the streams are
+	if save.Streams != nil {
+		engine.RNG.Seek(save.Streams)
+	}
+	return nil
+}
@@ -1,21 +1,32 @@
 package main
 
 import (
+	"time"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+// snapDistance is the farthest a sprite can move in an update and still be interpolated. Anything further is a teleport
+const snapDistance = 64
+
 // Sprite represents a sprite in a spritesheet
 type Sprite struct {
-	Animated bool
-
-	Color      rl.Color
-	Counter    int
-	Deleted    bool
-	Effects    map[string]func()
-	Frame      int // Tracks which cycle of animation the object should be in
-	FrameCount int
+	// Clip is the animation playing, if any. Without one the sprite is drawn from XPos, YPos
+	Clip *Clip
+	// Clips are the animations the sprite can play, by name
+	Clips   map[string]*Clip
+	Color   rl.Color
+	Deleted bool
+	Effects map[string]func()
+	// Elapsed is how long the current frame has been shown
+	Elapsed time.Duration
+	// Filepath is the spritesheet the texture was loaded from
+	Filepath string
+	Frame    int // Tracks which frame of the clip the sprite is on
 	// LevelX, LevelY represent the X,Y screen coords
 	LevelX, LevelY float32
+	// PrevX, PrevY are where the sprite was before the last update
+	PrevX, PrevY float32
 	// Toggle true to render the sprite in reverse
 	Reversed bool
 	// Scale is for rendering at a different scale
@@ -24,53 +35,91 @@ type Sprite struct {
 	Speed                     int
 	Texture                   rl.Texture2D
 	XPos, YPos, Width, Height float32 // Represents the sprite's parameters on a spritesheet
+	// snapshotted is true once there's a previous position to interpolate from
+	snapshotted bool
 }
 
 // Init sets the sprite's initial position on the provided spritesheet
 func (s *Sprite) Init(filepath string, x, y, w, h float32) {
 	s.Color = rl.White
-	s.Texture = rl.LoadTexture(filepath)
+	s.Filepath = filepath
+	s.Texture = Assets.Texture(filepath)
 	s.XPos = x
 	s.YPos = y
 	s.Width = w
 	s.Height = h
-	s.FrameCount = 1
 	s.Scale = 1
 	s.Speed = 1
 }
 
+// Release gives the sprite's texture back to the asset manager
+func (s *Sprite) Release() {
+	Assets.ReleaseTexture(s.Filepath)
+}
+
 // CanReap returns Sprite's Deleted bool
 func (s *Sprite) CanReap() bool {
 	return s.Deleted
 }
 
-// Draw renders the sprite to the screen in its frame of animation
-func (s *Sprite) Draw() {
-	rectangle := rl.NewRectangle(s.XPos+(float32(s.Frame)*s.Width), s.YPos, s.Width*s.Scale, s.Height)
+// Draw renders the sprite to the screen in its frame of animation, alpha of the way from where it was to where it is
+func (s *Sprite) Draw(alpha float32) {
+	rectangle := s.Source()
+	rectangle.Width *= s.Scale
 	if s.Reversed {
-		rectangle = rl.NewRectangle(s.XPos+(float32(s.Frame)*s.Width), s.YPos, -s.Width*s.Scale, s.Height)
+		rectangle.Width = -rectangle.Width
 	}
-	position := rl.NewVector2(s.LevelX, s.LevelY)
-	rl.DrawTextureRec(s.Texture, rectangle, position, s.Color)
+	rl.DrawTextureRec(s.Texture, rectangle, s.Position(alpha), s.Color)
 }
 
-// Update cycles the animation
-// Counter should increment roughly 60 times a second at 60 FPS, so 60 % 10 should occur about 6 times a second
-func (s *Sprite) Update() {
-	if s.Counter%10 == 0 && s.Animated {
-		s.Frame++
-		if s.Frame > s.FrameCount {
-			s.Frame = 1
-		}
-		if s.Counter == 60 {
-			s.Counter = 0
-		}
+// Source returns where the sprite's current frame is on the spritesheet
+func (s *Sprite) Source() rl.Rectangle {
+	if s.Clip == nil || len(s.Clip.Frames) == 0 {
+		return rl.NewRectangle(s.XPos, s.YPos, s.Width, s.Height)
 	}
+	return s.Clip.Frames[s.Frame%len(s.Clip.Frames)].Source
+}
 
-	if !s.Animated {
-		s.Frame = 0
+// Play switches the sprite to one of its clips by name. A clip that's already playing carries on where it was, and
+// a name the sprite has no clip for leaves the current one playing
+func (s *Sprite) Play(name string) {
+	clip, ok := s.Clips[name]
+	if !ok || clip == s.Clip {
+		return
+	}
+	s.Clip = clip
+	s.Frame = 0
+	s.Elapsed = 0
+}
+
+// Position returns where to draw the sprite, interpolated between where it was and where it is now by alpha, from 0 to 1
+func (s *Sprite) Position(alpha float32) rl.Vector2 {
+	dx, dy := s.LevelX-s.PrevX, s.LevelY-s.PrevY
+	if !s.snapshotted || dx*dx+dy*dy > snapDistance*snapDistance {
+		return rl.NewVector2(s.LevelX, s.LevelY)
+	}
+	return rl.NewVector2(s.PrevX+dx*alpha, s.PrevY+dy*alpha)
+}
+
+// Snapshot remembers the sprite's position before an update
+func (s *Sprite) Snapshot() {
+	s.PrevX, s.PrevY = s.LevelX, s.LevelY
+	s.snapshotted = true
+}
+
+// Update plays the clip on by a tick, moving to the next frame once the current one has been shown for its duration
+func (s *Sprite) Update() {
+	if s.Clip == nil || len(s.Clip.Frames) == 0 {
+		return
+	}
+	// A reloaded clip may have fewer frames than it did
+	s.Frame %= len(s.Clip.Frames)
+	s.Elapsed += time.Second / TickRate
+	// Frames shorter than a tick are still shown for one
+	if duration := s.Clip.Frames[s.Frame].Duration; s.Elapsed >= duration {
+		s.Elapsed -= duration
+		s.Frame = (s.Frame + 1) % len(s.Clip.Frames)
 	}
-	s.Counter++
 }
 
 // GetHitbox returns a rectangle to represent the entity hitbox
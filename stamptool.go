@@ -0,0 +1,265 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TiledTileset is a tileset building files can be drawn with: the spritesheet it's cut from, and the ramp of shades
+// it's drawn in, which the preview tints are recolored from
+type TiledTileset struct {
+	Ramp        ColorRamp
+	Spritesheet string
+}
+
+// TiledTilesets are the tilesets the game knows, by the name Tiled saves them under
+var TiledTilesets = map[string]TiledTileset{
+	"projectmute": {Ramp: MuteRamp, Spritesheet: "assets/sprites/projectmute.png"},
+}
+
+// previewStates are the tints of the building preview, in the order they're laid out in a stamp preview
+var previewStates = []string{"green", "yellow", "red"}
+
+// stampTileSize is the size of the game's tiles. Stamps are placed on the street in whole tiles, so anything else
+// won't line up
+const stampTileSize = 16
+
+// TiledTilesetOf returns the tileset a Tiled file is drawn with. Stamps are drawn from a single tileset, numbered
+// from 1 like the game's loader expects
+func TiledTilesetOf(t *Tiled) (TiledTileset, error) {
+	if len(t.Tilesets) != 1 {
+		return TiledTileset{}, fmt.Errorf("it uses %v tilesets, but stamps are drawn from exactly one", len(t.Tilesets))
+	}
+	tileset := t.Tilesets[0]
+	name := tileset.Name
+	for _, file := range []string{tileset.Source, tileset.Image} {
+		if file != "" {
+			// Tiled saves paths relative to wherever the artist keeps their files, on whatever system they're on, so
+			// only the name is any use
+			base := path.Base(strings.ReplaceAll(file, `\`, "/"))
+			name = strings.TrimSuffix(base, path.Ext(base))
+		}
+	}
+	known, ok := TiledTilesets[name]
+	if !ok {
+		names := []string{}
+		for name := range TiledTilesets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return TiledTileset{}, fmt.Errorf("unknown tileset %q, it should be one of %v", name, strings.Join(names, ", "))
+	}
+	if tileset.FirstGID != 1 {
+		return TiledTileset{}, fmt.Errorf("tileset %v starts at tile %v, but the game expects it to start at 1", name, tileset.FirstGID)
+	}
+	return known, nil
+}
+
+// ValidateTiled returns everything wrong with a Tiled file that would stop it being drawn properly with the palette
+func ValidateTiled(t *Tiled, palette *Palette) []error {
+	errs := []error{}
+	if t.TileWidth != stampTileSize || t.TileHeight != stampTileSize {
+		errs = append(errs, fmt.Errorf("tiles are %vx%v pixels, but the game's are %vx%v", t.TileWidth, t.TileHeight, stampTileSize, stampTileSize))
+	}
+	tileLayers := 0
+	for i, layer := range t.Layers {
+		if layer.Type != "" && layer.Type != "tilelayer" {
+			continue
+		}
+		tileLayers++
+		name := layer.Name
+		if name == "" {
+			name = fmt.Sprintf("%v", i+1)
+		}
+		if layer.Width != t.Width || layer.Height != t.Height {
+			errs = append(errs, fmt.Errorf("layer %q is %vx%v tiles, but the map is %vx%v", name, layer.Width, layer.Height, t.Width, t.Height))
+		}
+		if len(layer.Data) != layer.Width*layer.Height {
+			errs = append(errs, fmt.Errorf("layer %q has %v tiles, but %vx%v needs %v", name, len(layer.Data), layer.Width, layer.Height, layer.Width*layer.Height))
+		}
+		for j, tile := range layer.Data {
+			if tile == 0 {
+				continue
+			}
+			// Tiles are numbered from 1, and flipped tiles have their high bits set, which the game can't draw either
+			if _, ok := palette.Brushes[tile-1]; !ok {
+				x, y := j, 0
+				if layer.Width > 0 {
+					x, y = j%layer.Width, j/layer.Width
+				}
+				errs = append(errs, fmt.Errorf("layer %q has tile %v at %v,%v, but the tileset has %v tiles", name, tile, x, y, len(palette.Brushes)))
+			}
+		}
+	}
+	if tileLayers == 0 {
+		errs = append(errs, fmt.Errorf("there are no tile layers"))
+	}
+	return errs
+}
+
+// LoadStampPreview loads a Tiled file through the game's loader, checks it, and draws it with each palette variant
+// side by side: as it is, then tinted as each preview state
+func LoadStampPreview(filepath string) (*image.RGBA, []error) {
+	tiled, err := loadTiled(filepath)
+	if err != nil {
+		return nil, []error{err}
+	}
+	tileset, err := TiledTilesetOf(tiled)
+	if err != nil {
+		return nil, []error{err}
+	}
+	palette, sheet, err := NewImagePalette(tileset.Spritesheet, stampTileSize, stampTileSize, 0, 0)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if errs := ValidateTiled(tiled, palette); len(errs) > 0 {
+		return nil, errs
+	}
+
+	stamp := ParseTiled(tiled)
+	variants := []*Palette{palette}
+	for _, state := range previewStates {
+		variants = append(variants, &Palette{
+			Base:    palette.Spritesheet,
+			Brushes: palette.Brushes,
+			Recolor: Recolor{{From: tileset.Ramp, To: PreviewRamps[state]}},
+		})
+	}
+
+	width, height := int(stamp.Width), int(stamp.Height)
+	preview := image.NewRGBA(image.Rect(0, 0, len(variants)*(width+stampTileSize)-stampTileSize, height))
+	for i, variant := range variants {
+		variantSheet := image.Image(sheet)
+		if i > 0 {
+			recolored, err := variant.Image()
+			if err != nil {
+				return nil, []error{err}
+			}
+			variantSheet = recolored
+		}
+		img, err := variant.Rasterize(stamp, variantSheet)
+		if err != nil {
+			return nil, []error{err}
+		}
+		at := image.Pt(i*(width+stampTileSize), 0)
+		draw.Draw(preview, img.Bounds().Add(at), img, image.Point{}, draw.Src)
+	}
+	return preview, nil
+}
+
+// RunStampCommand runs `pixelopolis stamp`, which artists use to check building files before committing them, and
+// returns the exit code
+func RunStampCommand(args []string, out io.Writer) int {
+	usage := func() int {
+		fmt.Fprintln(out, "usage:")
+		fmt.Fprintln(out, "  pixelopolis stamp check <file.json>...           report problems with Tiled building files")
+		fmt.Fprintln(out, "  pixelopolis stamp preview <file.json> -o out.png draw the file as it is, then in each preview tint")
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+
+	flags := flag.NewFlagSet("stamp "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	assets := flags.String("assets", ".", "the directory the files are in, laid out like the repo")
+	output := flags.String("o", "", "write the preview to this PNG file")
+	scale := flags.Int("scale", 1, "scale the preview up, to see the pixels")
+	files, err := parseInterspersed(flags, args[1:])
+	if err != nil {
+		return 2
+	}
+	UseAssetOverride(*assets)
+
+	switch args[0] {
+	case "check":
+		if len(files) == 0 {
+			return usage()
+		}
+		failed := false
+		for _, file := range files {
+			_, errs := loadStampFile(file, *assets)
+			for _, err := range errs {
+				fmt.Fprintf(out, "%v: %v\n", file, err)
+			}
+			failed = failed || len(errs) > 0
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	case "preview":
+		if len(files) != 1 || *output == "" {
+			return usage()
+		}
+		preview, errs := loadStampFile(files[0], *assets)
+		for _, err := range errs {
+			fmt.Fprintf(out, "%v: %v\n", files[0], err)
+		}
+		if len(errs) > 0 {
+			return 1
+		}
+		if err := SaveScreenshot(enlarge(preview, *scale), *output); err != nil {
+			fmt.Fprintf(out, "Couldn't save the preview: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "Saved %v\n", *output)
+		return 0
+	}
+	return usage()
+}
+
+// loadStampFile previews a file given on the command line. Assets are read by their path inside the assets
+// directory, so the file's path is made relative to it
+func loadStampFile(file, assets string) (*image.RGBA, []error) {
+	absolute, err := filepath.Abs(file)
+	if err != nil {
+		return nil, []error{err}
+	}
+	root, err := filepath.Abs(assets)
+	if err != nil {
+		return nil, []error{err}
+	}
+	name, err := filepath.Rel(root, absolute)
+	if err != nil || strings.HasPrefix(name, "..") {
+		return nil, []error{fmt.Errorf("it isn't in the assets directory %v, pick another with -assets", assets)}
+	}
+	return LoadStampPreview(filepath.ToSlash(name))
+}
+
+// parseInterspersed parses flags that come before, after or between the arguments, and returns the arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// enlarge scales the image up by a whole factor, each pixel becoming a square
+func enlarge(img *image.RGBA, scale int) *image.RGBA {
+	if scale <= 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	large := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	for y := 0; y < large.Rect.Dy(); y++ {
+		for x := 0; x < large.Rect.Dx(); x++ {
+			large.SetRGBA(x, y, img.RGBAAt(bounds.Min.X+x/scale, bounds.Min.Y+y/scale))
+		}
+	}
+	return large
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiledTilesetOf(t *testing.T) {
+	tileset, err := TiledTilesetOf(&Tiled{Tilesets: []Tileset{{FirstGID: 1, Source: `..\..\Desktop\projectmute.tsx`}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "assets/sprites/projectmute.png", tileset.Spritesheet)
+
+	_, err = TiledTilesetOf(&Tiled{Tilesets: []Tileset{{FirstGID: 1, Image: "tiles/castle.png"}}})
+	assert.EqualError(t, err, `unknown tileset "castle", it should be one of projectmute`)
+	_, err = TiledTilesetOf(&Tiled{Tilesets: []Tileset{{FirstGID: 5, Name: "projectmute"}}})
+	assert.Error(t, err)
+	_, err = TiledTilesetOf(&Tiled{})
+	assert.Error(t, err)
+}
+
+func TestValidateTiled(t *testing.T) {
+	palette := &Palette{Brushes: BrushMap(32, 32, 16, 16, 0, 0)}
+	tiled := &Tiled{TileWidth: 16, TileHeight: 16, Width: 2, Height: 1, Layers: []Layer{
+		{Name: "walls", Width: 2, Height: 1, Data: []int{1, 4}},
+		{Name: "objects", Type: "objectgroup"},
+	}}
+	assert.Empty(t, ValidateTiled(tiled, palette))
+
+	tiled.TileWidth = 32
+	tiled.Layers = append(tiled.Layers,
+		Layer{Name: "doors", Width: 2, Height: 1, Data: []int{0, 5}},
+		Layer{Name: "windows", Width: 3, Height: 1, Data: []int{0, 0}},
+	)
+	errs := []string{}
+	for _, err := range ValidateTiled(tiled, palette) {
+		errs = append(errs, err.Error())
+	}
+	assert.Equal(t, []string{
+		"tiles are 32x16 pixels, but the game's are 16x16",
+		`layer "doors" has tile 5 at 1,0, but the tileset has 4 tiles`,
+		`layer "windows" is 3x1 tiles, but the map is 2x1`,
+		`layer "windows" has 2 tiles, but 3x1 needs 3`,
+	}, errs)
+}
+
+func TestStampCommand(t *testing.T) {
+	defer func() {
+		assetOverride = nil
+		layerAssets()
+	}()
+	out := &bytes.Buffer{}
+	assert.Equal(t, 0, RunStampCommand([]string{"check", "assets/buildings/slum/1.json", "assets/buildings/slum/church.json"}, out))
+
+	// Flags can come after the file, like artists tend to type them
+	preview := filepath.Join(t.TempDir(), "church.png")
+	assert.Equal(t, 0, RunStampCommand([]string{"preview", "assets/buildings/slum/church.json", "-o", preview, "-scale", "2"}, out))
+	file, err := os.Open(preview)
+	assert.NoError(t, err)
+	defer file.Close()
+	img, err := png.Decode(file)
+	assert.NoError(t, err)
+	// The church is 96 pixels square, drawn as it is and in three tints with a tile between each
+	assert.Equal(t, (4*96+3*16)*2, img.Bounds().Dx())
+	assert.Equal(t, 96*2, img.Bounds().Dy())
+
+	out.Reset()
+	assert.Equal(t, 1, RunStampCommand([]string{"check", "assets/buildings/missing.json"}, out))
+	assert.Contains(t, out.String(), "assets/buildings/missing.json: ")
+	assert.Equal(t, 2, RunStampCommand([]string{"preview", "assets/buildings/slum/church.json"}, out))
+	assert.Equal(t, 2, RunStampCommand(nil, out))
+}
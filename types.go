@@ -1,7 +1,7 @@
 package main
 
 import (
-	"math/rand"
+	"fmt"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -13,7 +13,7 @@ type Level struct {
 
 // Init takes a background file, converts it to texture for rendering the level background
 func (level *Level) Init(filepath string, width, height int32) {
-	image := rl.LoadImage(filepath)
+	image := rl.LoadImage(resolveAsset(filepath))
 	rl.ImageResize(image, width, height)
 	level.Texture = rl.LoadTextureFromImage(image)
 }
@@ -27,10 +27,10 @@ func (level *Level) Draw() {
 
 // Taxi represents a taxi that spawn people, bringing them into the city
 type Taxi struct {
+	Identity
 	Dosh       int
 	Effects    []func(*Taxi)
 	Engine     *Engine
-	Entities   *[]Entity
 	Passengers int
 	Sound      rl.Sound
 	SpawnRate  int
@@ -42,24 +42,37 @@ func (taxi *Taxi) CanReap() bool {
 	return false
 }
 
+// Category files the taxi under vehicles
+func (taxi *Taxi) Category() Category {
+	return CategoryVehicle
+}
+
+// Snapshot remembers where the taxi was before an update
+func (taxi *Taxi) Snapshot() {
+	taxi.Sprite.Snapshot()
+}
+
 // Draw renders the taxi sprite to the screen
 func (taxi *Taxi) Draw() {
-	taxi.Sprite.Draw()
+	taxi.Sprite.Draw(taxi.Engine.Alpha())
 }
 
 // Update drives the taxi along the X axis
 func (taxi *Taxi) Update() {
-	if taxi.Sprite.LevelX >= -taxi.Sprite.Width && taxi.Sprite.LevelX <= float32(rl.GetScreenWidth())+taxi.Sprite.Width {
+	rng := taxi.Engine.Rand(StreamTaxi)
+	if taxi.Sprite.LevelX >= -taxi.Sprite.Width && taxi.Sprite.LevelX <= float32(WorldWidth)+taxi.Sprite.Width {
+		taxi.Sprite.Play("drive")
 		taxi.Sprite.LevelX += 4
 	} else {
+		taxi.Sprite.Play("idle")
 		// If we're not in motion, respawn if we get a random 1
-		// rate is the rate of respawn. If we make the odds 1 in 60, we should expect to trigger this
-		// once a second. We instead want to trigger it once a minute
+		// rate is the rate of respawn. If we make the odds 1 in TickRate, we should expect to trigger this
+		// once a second. We instead want to trigger it every 10 seconds or so
 		if taxi.SpawnRate == 0 {
-			taxi.SpawnRate = 60 * 10
+			taxi.SpawnRate = TickRate * 10
 		}
 		// Randomly spawn a taxi to drop off a person, assuming we have the population allowance
-		if rand.Intn(taxi.SpawnRate) == 1 && taxi.Engine.PopulationMax > taxi.Engine.Population {
+		if rng.Intn(taxi.SpawnRate) == 1 && taxi.Engine.PopulationMax > taxi.Engine.Population {
 			taxi.Sprite.LevelX = -taxi.Sprite.Width
 			rl.PlaySound(taxi.Sound)
 		}
@@ -71,26 +84,23 @@ func (taxi *Taxi) Update() {
 		}
 	}
 
-	if taxi.Sprite.LevelX == float32(rl.GetScreenWidth()/2) {
-		randomizer := rand.Intn(4)
-
-		// Randomly pick between the available choices of characters on the sprite sheet
-		s := &Sprite{}
-		s.Init("assets/sprites/mega.png", 0, 864+float32(32*randomizer), 32, 32)
-		s.FrameCount = 4
+	if taxi.Sprite.LevelX == float32(WorldWidth/2) {
+		randomizer := rng.Intn(4)
 
 		for i := 0; i < taxi.Passengers; i++ {
-			p := &Person{Dosh: rand.Intn(100)}
+			p := &Person{Dosh: rng.Intn(100)}
 			p.Init(taxi.Engine)
-			p.Sprite = *s
+			// Randomly pick between the available choices of characters on the sprite sheet
+			p.Sprite.Init("assets/sprites/mega.png", 0, 864+float32(32*randomizer), 32, 32)
+			p.Sprite.Clips = MegaClips(fmt.Sprintf("person%v", randomizer))
 			p.Sprite.LevelX = taxi.Sprite.LevelX
 			p.Sprite.LevelY = taxi.Sprite.LevelY
 			p.Effects = append(p.Effects, Wander)
 			// Spawn a money bags passenger about 1 in 10 times
-			if rand.Intn(10) == 1 {
+			if rng.Intn(10) == 1 {
 				p.Effects = append(p.Effects, MoneyBags)
 			}
-			taxi.Engine.Entities = append(taxi.Engine.Entities, p)
+			taxi.Engine.Spawn(p)
 		}
 		// fmt.Println("SPAWN at: %d, %d", p.Sprite.LevelX, p.Sprite.LevelY)
 
@@ -98,11 +108,12 @@ func (taxi *Taxi) Update() {
 		coin := NewCoin(taxi.Engine, 2.5, taxi.Sprite.LevelX, taxi.Sprite.LevelY)
 
 		// Add the taxi and the fare tax to the entity bag
-		taxi.Engine.Entities = append(taxi.Engine.Entities, coin)
+		taxi.Engine.Spawn(coin)
 	}
+	taxi.Sprite.Update()
 }
 
 // GetHitbox returns a rectangle to represent the entity hitbox
 func (taxi *Taxi) GetHitbox() rl.Rectangle {
-	return rl.NewRectangle(taxi.Sprite.XPos, taxi.Sprite.YPos, taxi.Sprite.Width, taxi.Sprite.Height)
+	return rl.NewRectangle(taxi.Sprite.LevelX, taxi.Sprite.LevelY, taxi.Sprite.Width, taxi.Sprite.Height)
 }
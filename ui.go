@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/gen2brain/raylib-go/raygui"
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -24,8 +23,10 @@ type GraphicButton struct {
 // UI is meants to hold buttons and be overlayed on top of the screen
 type UI struct {
 	BuildingCache *Building // Stores building to be passed between Update and Draw
-	Buttons       map[string]*Button
-	ButtonValues  map[string]bool
+	// buildingButtons counts the buttons added with AddBuildingButton, to lay out the next one
+	buildingButtons int
+	Buttons         map[string]*Button
+	ButtonValues    map[string]bool
 	// Used to update if our preview cursor is collided
 	CursorCollided bool
 	Decorations    []Decoration
@@ -42,6 +43,7 @@ type UI struct {
 	// 2 - City Tileset in Green
 	// 3 - City Tileset in Yellow
 	// 4 - City Tileset in Red
+	// The coloured tilesets are recolored from the blue one when the UI starts
 	Palettes         map[int]*Palette
 	ScreenX, ScreenY int32
 	SoundConfirm     rl.Sound
@@ -57,13 +59,14 @@ func (ui *UI) Init() {
 	ui.Palettes = make(map[int]*Palette)
 	ui.Palettes[0] = GetUIPalette()
 	ui.Palettes[1] = GetProjectMegaPalette("assets/sprites/projectmute.png")
-	ui.Palettes[2] = GetProjectMegaPalette("assets/sprites/projectmuteG.png")
-	ui.Palettes[3] = GetProjectMegaPalette("assets/sprites/projectmuteY.png")
-	ui.Palettes[4] = GetProjectMegaPalette("assets/sprites/projectmuteR.png")
+	ui.Palettes[1].AddPreviewVariants(MuteRamp)
+	ui.Palettes[2] = ui.Palettes[1].Variants["green"]
+	ui.Palettes[3] = ui.Palettes[1].Variants["yellow"]
+	ui.Palettes[4] = ui.Palettes[1].Variants["red"]
 
-	ui.SoundConfirm = rl.LoadSound("assets/sounds/confirm.mp3")
-	ui.SoundSelect = rl.LoadSound("assets/sounds/select.mp3")
-	ui.SoundCancel = rl.LoadSound("assets/sounds/cancel.mp3")
+	ui.SoundConfirm = Assets.Sound("assets/sounds/confirm.mp3")
+	ui.SoundSelect = Assets.Sound("assets/sounds/select.mp3")
+	ui.SoundCancel = Assets.Sound("assets/sounds/cancel.mp3")
 	ui.Toggles = make(map[string]bool)
 	ui.BuildingCache = &Building{}
 }
@@ -76,48 +79,90 @@ func (ui *UI) Draw() {
 		f()
 	}
 
-	// if we're in building preview mode, look for collisions, then print whichever building stamp is in
-	// the building cache
-	if len(ui.Toggles) > 0 && ui.Toggles["drawPreview"] {
-		if ui.CursorCollided {
-			ui.BuildingCache.Stamp.Palette = ui.Palettes[3]
-		} else if ui.Engine.Dosh < ui.BuildingCache.Cost {
-			ui.BuildingCache.Stamp.Palette = ui.Palettes[4]
-		} else {
-			ui.BuildingCache.Stamp.Palette = ui.Palettes[2]
-		}
+	fpsOffset := ui.ScreenX - rl.MeasureText("FPS: 000  ", 18)
+	rl.DrawText(fmt.Sprintf("FPS: %v", rl.GetFPS()), fpsOffset, 20, 18, rl.Gold)
+	if ui.Toggles["assets"] {
+		report := Assets.Report().String()
+		rl.DrawText(report, ui.ScreenX-rl.MeasureText(report+"  ", 18), 40, 18, rl.Gold)
+	}
+}
+
+// DrawPreview renders the building being placed where it would go on the street. It's drawn with the world rather
+// than the UI, so it scrolls with the camera
+func (ui *UI) DrawPreview() {
+	if ui.Toggles["drawPreview"] {
 		ui.BuildingCache.Draw()
 	}
+}
 
-	fpsOffset := ui.ScreenX - rl.MeasureText("FPS: 000  ", 18)
-	rl.DrawText(fmt.Sprintf("FPS: %v", rl.GetFPS()), fpsOffset, 20, 18, rl.Gold)
+// BakePreview tints the building being placed for whether it can go where it is, and bakes it ahead of drawing
+func (ui *UI) BakePreview() {
+	if !ui.Toggles["drawPreview"] {
+		return
+	}
+	state := "green"
+	if ui.CursorCollided {
+		state = "yellow"
+	} else if ui.Engine.Dosh < ui.BuildingCache.Cost {
+		state = "red"
+	}
+	// Buildings with a palette of their own are tinted with its variants, or previewed as they are without any
+	palette := ui.Palettes[1]
+	if ui.BuildingCache.Palette != nil {
+		palette = ui.BuildingCache.Palette
+	}
+	ui.BuildingCache.Stamp.Palette = palette
+	if variant, ok := palette.Variants[state]; ok {
+		ui.BuildingCache.Stamp.Palette = variant
+	}
+	ui.BuildingCache.Bake()
 }
 
 // Update renders the UI buttons so that it can store the values of the button bools to the ButtonValues map
 func (ui *UI) Update() {
 	for k, v := range ui.Buttons {
-		ui.ButtonValues[k] = raygui.Button(rl.NewRectangle(v.XPos, v.YPos, v.Width, v.Height), v.Text)
+		ui.ButtonValues[k] = Input.Button(k, raygui.Button(rl.NewRectangle(v.XPos, v.YPos, v.Width, v.Height), v.Text))
 	}
 
-	// If the house button is clicked, render  the appropriate preview
-	// The code that determines this is in the ui
-	if ui.ButtonValues["house"] || ui.ButtonValues["slum"] || ui.ButtonValues["apartment"] || ui.ButtonValues["church"] {
+	// If a building's button is clicked, render the appropriate preview
+	// Every building in the catalog has a build button named after it
+	for name, build := range Catalog {
+		if !ui.ButtonValues[buildButton(name)] {
+			continue
+		}
 		rl.PlaySound(ui.SoundSelect)
-		// House button hook here
 		ui.Toggles["drawPreview"] = !ui.Toggles["drawPreview"]
-		if ui.ButtonValues["house"] {
-			// build our bespoke building and store it in cache
-			ui.BuildingCache = GetHouse(ui.Engine, ui.Palettes[2])
-		}
-		if ui.ButtonValues["slum"] {
-			ui.BuildingCache = GetSlum(ui.Engine, ui.Palettes[2])
-		}
-		if ui.ButtonValues["apartment"] {
-			ui.BuildingCache = GetApartment(ui.Engine, ui.Palettes[2])
-		}
-		if ui.ButtonValues["church"] {
-			ui.BuildingCache = GetChurch(ui.Engine, ui.Palettes[2])
-		}
+		// build our bespoke building and store it in cache, letting go of the last one's baked preview
+		ui.BuildingCache.Release()
+		ui.BuildingCache = build(ui.Engine, ui.Palettes[2])
+		break
+	}
+
+	// Time controls, from the buttons or the keyboard
+	if ui.ButtonValues["pause"] || Input.KeyPressed(Keybindings["pause"]) {
+		ui.Engine.TogglePause()
+	}
+	if ui.ButtonValues["normal"] || Input.KeyPressed(Keybindings["normal"]) {
+		ui.Engine.SetTimeScale(Normal)
+	}
+	if ui.ButtonValues["fast"] || Input.KeyPressed(Keybindings["fast"]) {
+		ui.Engine.SetTimeScale(Fast)
+	}
+	if ui.ButtonValues["fastest"] || Input.KeyPressed(Keybindings["fastest"]) {
+		ui.Engine.SetTimeScale(Fastest)
+	}
+	if ui.ButtonValues["step"] || Input.KeyPressed(Keybindings["step"]) {
+		ui.Engine.Step()
+	}
+
+	if Input.KeyPressed(Keybindings["assets"]) {
+		ui.Toggles["assets"] = !ui.Toggles["assets"]
+	}
+
+	if ui.ButtonValues["disasters"] {
+		rl.PlaySound(ui.SoundSelect)
+		ui.Engine.DisasterFrequency = ui.Engine.DisasterFrequency.Next()
+		ui.Buttons["disasters"].Text = fmt.Sprintf("disasters: %v", ui.Engine.DisasterFrequency)
 	}
 
 	for i, e := range ui.Events {
@@ -128,52 +173,56 @@ func (ui *UI) Update() {
 				rl.PlaySound(e.Sound)
 				e.Triggered = true
 			}
-			if rl.IsKeyPressed(rl.KeySpace) && !e.Done {
+			if Input.KeyPressed(Keybindings["space"]) && !e.Done {
 				e.Done = true
 				ui.Halt = false
 			}
 			if e.Done {
+				e.Release()
 				ui.Events = append(ui.Events[:i], ui.Events[i+1:]...)
 			}
 		}
 	}
 
 	if len(ui.Toggles) > 0 && ui.Toggles["drawPreview"] {
-		mouseX := rl.GetMouseX()
-		ui.BuildingCache.Stamp.LevelX = float32(mouseX) - (ui.BuildingCache.Stamp.Width / 2)
+		mouseX := ui.Engine.Camera.WorldX(Input.MouseX())
+		ui.BuildingCache.Stamp.LevelX = mouseX - (ui.BuildingCache.Stamp.Width / 2)
 		ui.BuildingCache.Stamp.LevelY = float32(ui.GroundLevel) - ui.BuildingCache.Stamp.Height + 16
 
-		ui.CursorCollided = ui.Engine.IsCollidedWithType(ui.BuildingCache, reflect.TypeOf(&Building{}))
+		ui.CursorCollided = ui.Engine.IsCollidedWith(ui.BuildingCache, CategoryBuilding)
 
 		// enable right click to exit
-		if rl.IsMouseButtonPressed(rl.MouseRightButton) {
+		if Input.MousePressed(rl.MouseRightButton) {
 			rl.PlaySound(ui.SoundCancel)
 			ui.Toggles["drawPreview"] = !ui.Toggles["drawPreview"]
 		}
 
 		// left click to place buildings
-		if rl.IsMouseButtonDown(rl.MouseLeftButton) && !ui.CursorCollided && ui.Engine.Dosh >= ui.BuildingCache.Cost && rl.GetMouseY() <= ui.GroundLevel+100 {
+		if Input.MouseDown(rl.MouseLeftButton) && !ui.CursorCollided && ui.Engine.Dosh >= ui.BuildingCache.Cost && Input.MouseY() <= ui.GroundLevel+100 {
 			rl.PlaySound(ui.SoundConfirm)
 			ui.Engine.Dosh -= ui.BuildingCache.Cost
-			ui.Engine.PopulationMax += ui.BuildingCache.Population
 			ui.Toggles["drawPreview"] = !ui.Toggles["drawPreview"]
 
-			// Create new instance so pointer doesn't change all buildings all the time
-			// TODO - Refactor this and other invocations to use a helper to make sure its setup
-			building := &Building{}
-			building.Stamp = ui.BuildingCache.Stamp
-			building.Stamp.Palette = ui.Palettes[1]
-			building.Stamp.LevelX = float32(mouseX) - (building.Stamp.Width / 2)
-			building.Stamp.LevelY = float32(GroundLevel) - building.Stamp.Height + 16
-			building.Engine = ui.Engine
-
-			// Buildings to the front so they are rendered in the back
-			ui.Engine.Entities = append([]Entity{building}, ui.Engine.Entities...)
+			PlaceBuilding(ui.Engine, ui.BuildingCache, ui.Palettes[1], mouseX-(ui.BuildingCache.Stamp.Width/2))
 		}
 	}
 
 }
 
+// AddBuildingButton adds a button for a building in the catalog, like one a mod adds. They're laid out in columns of two
+// to the right of the time controls
+func (ui *UI) AddBuildingButton(name, label string) {
+	column, row := ui.buildingButtons/2, ui.buildingButtons%2
+	ui.Buttons[buildButton(name)] = &Button{label, 640 + float32(column*90), float32(ui.ScreenY - 130 + int32(row)*50), 80, 40}
+	ui.buildingButtons++
+}
+
+// buildButton returns the name of a building's build button. They're kept apart from the UI's own buttons, so a
+// building named like one can't press it
+func buildButton(name string) string {
+	return "build:" + name
+}
+
 // GetMainUI composes the UI for the main game loop
 func GetMainUI(engine *Engine, groundLevel int, ScreenX, ScreenY int32) *UI {
 	//height := (ScreenY / 5)
@@ -193,10 +242,19 @@ func GetMainUI(engine *Engine, groundLevel int, ScreenX, ScreenY int32) *UI {
 	ui.Buttons = make(map[string]*Button)
 	ui.ButtonValues = make(map[string]bool)
 
-	ui.Buttons["house"] = &Button{"$1 - house", 10, float32(ScreenY - 130), 80, 40}
-	ui.Buttons["slum"] = &Button{"$10 - slum", 10, float32(ScreenY - 80), 80, 40}
-	ui.Buttons["apartment"] = &Button{"$100 - apt", 100, float32(ScreenY - 130), 80, 40}
-	ui.Buttons["church"] = &Button{"$300 - church", 100, float32(ScreenY - 80), 80, 40}
+	ui.Buttons[buildButton("house")] = &Button{"$1 - house", 10, float32(ScreenY - 130), 80, 40}
+	ui.Buttons[buildButton("slum")] = &Button{"$10 - slum", 10, float32(ScreenY - 80), 80, 40}
+	ui.Buttons[buildButton("apartment")] = &Button{"$100 - apt", 100, float32(ScreenY - 130), 80, 40}
+	ui.Buttons[buildButton("church")] = &Button{"$300 - church", 100, float32(ScreenY - 80), 80, 40}
+	ui.Buttons[buildButton("firestation")] = &Button{"$50 - fire stn", 190, float32(ScreenY - 130), 80, 40}
+	ui.Buttons[buildButton("militia")] = &Button{"$75 - militia", 190, float32(ScreenY - 80), 80, 40}
+	ui.Buttons["disasters"] = &Button{fmt.Sprintf("disasters: %v", engine.DisasterFrequency), 280, float32(ScreenY - 130), 110, 40}
+
+	ui.Buttons["pause"] = &Button{"||", 400, float32(ScreenY - 130), 30, 40}
+	ui.Buttons["normal"] = &Button{">", 435, float32(ScreenY - 130), 30, 40}
+	ui.Buttons["fast"] = &Button{">>", 470, float32(ScreenY - 130), 30, 40}
+	ui.Buttons["fastest"] = &Button{">>>", 505, float32(ScreenY - 130), 30, 40}
+	ui.Buttons["step"] = &Button{"step", 400, float32(ScreenY - 80), 135, 40}
 
 	padding := rl.MeasureText("Population: 100000 / 100000", 18)
 	yOffset := (ui.ScreenY / 12)
@@ -207,6 +265,20 @@ func GetMainUI(engine *Engine, groundLevel int, ScreenX, ScreenY int32) *UI {
 	ui.DrawFuncs = append(ui.DrawFuncs, func() {
 		rl.DrawText(fmt.Sprintf("Dosh: $%.2f", ui.Engine.Dosh), ui.ScreenX-(padding), ui.ScreenY-(yOffset+18), 18, rl.RayWhite)
 	})
+	ui.DrawFuncs = append(ui.DrawFuncs, func() {
+		date := ui.Engine.Calendar.String()
+		if ui.Engine.Weather != nil {
+			date = fmt.Sprintf("%v - %v", date, ui.Engine.Weather.Kind())
+		}
+		rl.DrawText(date, ui.ScreenX-(padding), ui.ScreenY-(yOffset+36), 18, rl.RayWhite)
+	})
+	ui.DrawFuncs = append(ui.DrawFuncs, func() {
+		speed := fmt.Sprintf("Speed: %vx", int(ui.Engine.TimeScale))
+		if ui.Engine.TimeScale == Paused {
+			speed = "Paused"
+		}
+		rl.DrawText(speed, 545, ui.ScreenY-120, 18, rl.RayWhite)
+	})
 	return ui
 }
 
@@ -1,82 +1,74 @@
 package main
 
 import (
+	"math"
 	"math/rand"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-// Droplet stores the coordinates and how to draw it
-type Droplet struct {
+// Rain satisfies entity so it can be used on menus and in game. Tune the speed, size and density for rain, snow or anything
+// else that falls out of the sky. The droplets themselves are particles in an Emitter
+type Rain struct {
 	Color rl.Color
-	// Allows for droplets to fall at varying rates
-	Speed      int
-	XPos, YPos int
-}
-
-// CanReap returns true when the droplet is off screen
-func (d *Droplet) CanReap() bool {
-	return d.YPos > int(rl.GetScreenHeight())
+	// Density is the average number of droplets spawned each update
+	Density float64
+	Done    bool
+	// Drift pushes droplets along the X axis, for snow flurries and storm winds
+	Drift   int
+	Emitter *Emitter
+	Music   rl.Music
+	Size    int
+	// Speed is the slowest a droplet will fall, SpeedVariance is how much faster than that a droplet may fall
+	Speed, SpeedVariance int
 }
 
-// Draw renders the droplet to the screen
-func (d *Droplet) Draw() {
-	rl.DrawRectangleRec(rl.NewRectangle(float32(d.XPos), float32(d.YPos), 4, 4), d.Color)
+// NewRain returns a light rain in the given color
+func NewRain(color rl.Color) *Rain {
+	return &Rain{Color: color, Density: 0.5, Emitter: &Emitter{}, Size: 4, Speed: 6, SpeedVariance: 10}
 }
 
-// Update imposes gravity on the droplet
-func (d *Droplet) Update() {
-	d.YPos += d.Speed
-}
-
-// Rain satisfies entity so it can be used on menus and in game
-type Rain struct {
-	Color    rl.Color
-	Done     bool
-	Droplets []*Droplet
-	Music    rl.Music
-}
-
-// CanReap returns true when the droplet is off screen ( > rl.GetScreenHeight )
+// CanReap returns Rain.Done
 func (r *Rain) CanReap() bool {
 	return r.Done
 }
 
 // Draw renders each droplet to the screen
 func (r *Rain) Draw() {
-	for _, droplet := range r.Droplets {
-		droplet.Draw()
-	}
+	r.Emitter.Draw()
 }
 
 // Init loads our SFX in
 func (r *Rain) Init() {
-	r.Music = rl.LoadMusicStream("assets/music/rain.mp3")
+	r.Music = Assets.Music("assets/music/rain.mp3")
 	rl.PlayMusicStream(r.Music)
 	rl.SetMusicVolume(r.Music, .08)
 }
 
-// Update manages the rain inventory for droplets
-// TODO - Rain is just a faster snow. Refactor to take a base speed for reuse between rain/snow
+// IsHeavy returns true when it's pouring
+func (r *Rain) IsHeavy() bool {
+	return r.Density >= 2
+}
+
+// Update tunes the emitter to the rain's settings, then lets it rain
 func (r *Rain) Update() {
-	rl.UpdateMusicStream(r.Music)
-
-	for i, droplet := range r.Droplets {
-		droplet.Update()
-		// Once the droplet is offscreen, clean it up
-		if droplet.YPos > rl.GetScreenHeight() {
-			if i == 0 {
-				r.Droplets = r.Droplets[1:]
-				continue
-			}
-			r.Droplets = append(r.Droplets[0:i], r.Droplets[i+1:]...)
-		}
+	width, height := float32(rl.GetScreenWidth()), float32(rl.GetScreenHeight())
+	speed := float32(r.Speed)
+	if speed < 1 {
+		speed = 1
 	}
+	// Start drifting droplets upwind so they still cover the screen
+	upwind := float32(r.Drift) * (height / speed) / 2
 
-	if rand.Intn(2) == 1 {
-		droplet := &Droplet{Speed: 6 + rand.Intn(10), XPos: rand.Intn(rl.GetScreenWidth()), YPos: -4, Color: r.Color}
-		r.Droplets = append(r.Droplets, droplet)
-	}
+	r.Emitter.Area = rl.NewRectangle(-upwind, float32(-r.Size), width, 1)
+	r.Emitter.Bounds = rl.NewRectangle(-width, float32(-r.Size*2), width*3, height+float32(r.Size*2))
+	r.Emitter.ColorStart, r.Emitter.ColorEnd = r.Color, r.Color
+	r.Emitter.Lifetime = Range{height, height}
+	r.Emitter.Rate = r.Density
+	r.Emitter.SizeStart, r.Emitter.SizeEnd = float32(r.Size), float32(r.Size)
+	r.Emitter.VelocityX = Range{float32(r.Drift), float32(r.Drift)}
+	r.Emitter.VelocityY = Range{speed, speed + float32(r.SpeedVariance)}
+	r.Emitter.Update()
 }
 
 // GoAway stops our music
@@ -90,3 +82,275 @@ func (r *Rain) GoAway() {
 func (r *Rain) GetHitbox() rl.Rectangle {
 	return rl.NewRectangle(0, 0, 0, 0)
 }
+
+// WeatherKind enumerates the types of weather the city can see
+type WeatherKind int
+
+const (
+	Clear WeatherKind = iota
+	Rainy
+	Storm
+	Snow
+	Fog
+	AcidRain
+)
+
+// String returns the name of the weather
+func (k WeatherKind) String() string {
+	switch k {
+	case Rainy:
+		return "rain"
+	case Storm:
+		return "storm"
+	case Snow:
+		return "snow"
+	case Fog:
+		return "fog"
+	case AcidRain:
+		return "acid rain"
+	}
+	return "clear"
+}
+
+// WeatherPreset describes how a type of weather looks, sounds and how the citizens feel about it
+type WeatherPreset struct {
+	Color rl.Color
+	// Darkness is how much the weather dims the light cycle, from 0 to 1
+	Darkness float64
+	Density  float64
+	Drift    int
+	// Fog is the alpha of the haze drawn over the city
+	Fog float64
+	// Shelter sends citizens indoors until it passes
+	Shelter              bool
+	Size                 int
+	Speed, SpeedVariance int
+	// Volume of the rain ambience
+	Volume float64
+}
+
+// WeatherPresets holds the settings for each kind of weather
+var WeatherPresets = map[WeatherKind]WeatherPreset{
+	Clear:    {Color: rl.NewColor(57, 16, 90, 200), Size: 4, Speed: 6, SpeedVariance: 10},
+	Rainy:    {Color: rl.NewColor(57, 16, 90, 200), Darkness: 0.15, Density: 0.8, Size: 4, Speed: 6, SpeedVariance: 10, Volume: 0.08},
+	Storm:    {Color: rl.NewColor(40, 20, 80, 220), Darkness: 0.4, Density: 4, Drift: 3, Shelter: true, Size: 4, Speed: 10, SpeedVariance: 10, Volume: 0.3},
+	Snow:     {Color: rl.NewColor(240, 240, 255, 220), Darkness: 0.1, Density: 0.6, Drift: 1, Size: 3, Speed: 1, SpeedVariance: 2},
+	Fog:      {Color: rl.NewColor(57, 16, 90, 200), Darkness: 0.2, Fog: 140, Size: 4, Speed: 6, SpeedVariance: 10},
+	AcidRain: {Color: rl.NewColor(120, 220, 40, 200), Darkness: 0.25, Density: 1.2, Shelter: true, Size: 4, Speed: 6, SpeedVariance: 10, Volume: 0.12},
+}
+
+// Weather is an entity that drives the city's weather, cycling between the presets and blending between them as it changes
+type Weather struct {
+	Identity
+	// Counter counts down the updates until the weather changes
+	Counter int
+	Current WeatherKind
+	Engine  *Engine
+	// Flash is the brightness of a lightning strike, fading each update
+	Flash float64
+	Next  WeatherKind
+	Rain  *Rain
+	// Transition tracks the blend from Current to Next, from 0 to 1
+	Transition float64
+	// Weights are the relative odds of each kind of weather being picked next
+	Weights map[WeatherKind]int
+}
+
+// NewWeather returns a weather system that starts off clear
+func NewWeather(engine *Engine) *Weather {
+	rng := engine.Rand(StreamWeather)
+	weather := &Weather{
+		Counter:    weatherDuration(rng),
+		Engine:     engine,
+		Rain:       NewRain(WeatherPresets[Clear].Color),
+		Transition: 1,
+		Weights: map[WeatherKind]int{
+			Clear:    40,
+			Rainy:    25,
+			Storm:    10,
+			Snow:     10,
+			Fog:      10,
+			AcidRain: 5,
+		},
+	}
+	weather.Rain.Emitter.RNG = engine.Rand(StreamParticles)
+	weather.Rain.Init()
+	weather.apply()
+	return weather
+}
+
+// weatherDuration returns how many updates a spell of weather lasts, somewhere between 1 and 3 minutes
+func weatherDuration(rng *rand.Rand) int {
+	return int(rate) + rng.Intn(int(rate)*2)
+}
+
+// Kind returns whichever weather is dominant in the current transition
+func (w *Weather) Kind() WeatherKind {
+	if w.Transition < 0.5 {
+		return w.Current
+	}
+	return w.Next
+}
+
+// Set starts a transition into the given weather
+func (w *Weather) Set(kind WeatherKind) {
+	w.Current = w.Kind()
+	w.Next = kind
+	w.Transition = 0
+	w.Counter = weatherDuration(w.Engine.Rand(StreamWeather))
+}
+
+// Shelter returns true when the weather is bad enough that citizens should head indoors
+func (w *Weather) Shelter() bool {
+	return WeatherPresets[w.Kind()].Shelter
+}
+
+// Light dims the given light cycle color by however dark the weather is
+func (w *Weather) Light(color rl.Color) rl.Color {
+	dim := 1 - w.blend(WeatherPresets[w.Current].Darkness, WeatherPresets[w.Next].Darkness)
+	return rl.NewColor(uint8(float64(color.R)*dim), uint8(float64(color.G)*dim), uint8(float64(color.B)*dim), color.A)
+}
+
+// blend returns a value between from and to, according to how far along the transition is
+func (w *Weather) blend(from, to float64) float64 {
+	return from + (to-from)*w.Transition
+}
+
+// CanReap the weather never goes away, it just clears up
+func (w *Weather) CanReap() bool {
+	return false
+}
+
+// Category files the weather under effects
+func (w *Weather) Category() Category {
+	return CategoryEffect
+}
+
+// Draw renders the droplets, any fog over the city and lightning flashes
+func (w *Weather) Draw() {
+	w.Rain.Draw()
+
+	screen := rl.NewRectangle(0, 0, float32(rl.GetScreenWidth()), float32(rl.GetScreenHeight()))
+	fog := w.blend(WeatherPresets[w.Current].Fog, WeatherPresets[w.Next].Fog)
+	if fog > 0 {
+		rl.DrawRectangleRec(screen, rl.NewColor(180, 180, 190, uint8(fog)))
+	}
+	if w.Flash > 0 {
+		rl.DrawRectangleRec(screen, rl.NewColor(255, 255, 255, uint8(w.Flash)))
+	}
+}
+
+// Render queues the weather over the whole street
+func (w *Weather) Render(queue *RenderQueue) {
+	queue.Push(LayerWeather, 0, w.Draw)
+}
+
+// Update counts down to the next change in weather, and blends the rain towards the next preset
+func (w *Weather) Update() {
+	w.Counter--
+	if w.Counter <= 0 {
+		w.Set(w.pick())
+	}
+
+	if w.Transition < 1 {
+		// Transitions take about 5 seconds
+		w.Transition = math.Min(w.Transition+1/(TickRate*5.0), 1)
+		if w.Transition == 1 {
+			w.Current = w.Next
+		}
+	}
+	w.apply()
+
+	if w.Flash > 0 {
+		w.Flash -= 10
+	}
+	if w.Kind() == Storm && w.Engine.Rand(StreamWeather).Intn(TickRate*8) == 1 {
+		w.Flash = 180
+	}
+
+	w.Rain.Update()
+}
+
+// apply blends the presets of the current and next weather onto the rain
+func (w *Weather) apply() {
+	from, to := WeatherPresets[w.Current], WeatherPresets[w.Next]
+	w.Rain.Density = w.blend(from.Density, to.Density)
+	w.Rain.Drift = int(math.Round(w.blend(float64(from.Drift), float64(to.Drift))))
+	w.Rain.Size = int(math.Round(w.blend(float64(from.Size), float64(to.Size))))
+	w.Rain.Speed = int(math.Round(w.blend(float64(from.Speed), float64(to.Speed))))
+	w.Rain.SpeedVariance = int(math.Round(w.blend(float64(from.SpeedVariance), float64(to.SpeedVariance))))
+	w.Rain.Color = rl.NewColor(
+		uint8(w.blend(float64(from.Color.R), float64(to.Color.R))),
+		uint8(w.blend(float64(from.Color.G), float64(to.Color.G))),
+		uint8(w.blend(float64(from.Color.B), float64(to.Color.B))),
+		uint8(w.blend(float64(from.Color.A), float64(to.Color.A))),
+	)
+	// Droplets are hard to make out against the night sky, so we lighten them up
+	if w.Engine != nil && w.Engine.Calendar.IsNight() && w.Kind() != Snow {
+		w.Rain.Color = rl.RayWhite
+	}
+	rl.SetMusicVolume(w.Rain.Music, float32(w.blend(from.Volume, to.Volume)))
+}
+
+// pick rolls for the next weather using the weights
+func (w *Weather) pick() WeatherKind {
+	total := 0
+	for _, weight := range w.Weights {
+		total += weight
+	}
+	if total == 0 {
+		return Clear
+	}
+
+	roll := w.Engine.Rand(StreamWeather).Intn(total)
+	// Walk the kinds in order so the roll maps to the same weather every time
+	for kind := Clear; kind <= AcidRain; kind++ {
+		roll -= w.Weights[kind]
+		if roll < 0 {
+			return kind
+		}
+	}
+	return Clear
+}
+
+// GetHitbox weather covers the whole street
+func (w *Weather) GetHitbox() rl.Rectangle {
+	return rl.NewRectangle(0, 0, float32(WorldWidth), float32(rl.GetScreenHeight()))
+}
+
+// SeekShelter is an effect that sends a Person to the nearest building when the weather turns, and keeps them indoors
+// until it clears up. Every citizen gets it when they're initialised, and Wander walks them to the door
+func SeekShelter(person *Person) {
+	weather := person.Engine.Weather
+	if weather == nil || !weather.Shelter() {
+		person.Sheltered = false
+		return
+	}
+	if person.Sheltered || person.Dragged || person.IsFalling() {
+		return
+	}
+
+	var shelter *Building
+	nearest := math.MaxFloat64
+	for _, building := range person.Engine.Buildings() {
+		hitbox := building.GetHitbox()
+		distance := math.Abs(float64(hitbox.X + hitbox.Width/2 - person.Sprite.LevelX))
+		if distance < nearest {
+			nearest = distance
+			shelter = building
+		}
+	}
+	if shelter == nil {
+		return
+	}
+
+	hitbox := shelter.GetHitbox()
+	door := float32(math.Round(float64(hitbox.X + hitbox.Width/2)))
+	if math.Abs(float64(person.Sprite.LevelX-door)) < float64(person.Sprite.Speed) {
+		person.Sheltered = true
+		person.OnTask = false
+		return
+	}
+	person.OnTask = true
+	person.WaypointX = door
+}
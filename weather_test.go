@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestWeather returns weather for the engine without loading its rain's music
+func newTestWeather(engine *Engine, kind WeatherKind) *Weather {
+	weather := &Weather{Counter: int(rate), Current: kind, Engine: engine, Next: kind, Rain: &Rain{Emitter: &Emitter{}}, Transition: 1}
+	weather.Rain.Emitter.RNG = engine.Rand(StreamParticles)
+	weather.apply()
+	engine.Weather = weather
+	return weather
+}
+
+func TestWeatherPick(t *testing.T) {
+	picks := func(seed int64, weights map[WeatherKind]int) map[WeatherKind]int {
+		engine := &Engine{RNG: NewRNG(seed)}
+		weather := newTestWeather(engine, Clear)
+		weather.Weights = weights
+		counts := map[WeatherKind]int{}
+		for i := 0; i < 10000; i++ {
+			counts[weather.pick()]++
+		}
+		return counts
+	}
+
+	// The same seed always picks the same weather
+	seasonal := SeasonModifiers[Spring].Weather
+	assert.Equal(t, picks(3, seasonal), picks(3, seasonal))
+
+	// Nothing with no weight is ever picked, and the rest come up in proportion to their weights
+	counts := picks(3, map[WeatherKind]int{Rainy: 1, Storm: 3})
+	assert.Equal(t, 10000, counts[Rainy]+counts[Storm])
+	assert.InDelta(t, 3, float64(counts[Storm])/float64(counts[Rainy]), 0.3)
+
+	assert.Equal(t, map[WeatherKind]int{Clear: 10000}, picks(3, map[WeatherKind]int{}))
+}
+
+func TestWeatherTransition(t *testing.T) {
+	engine := &Engine{RNG: NewRNG(1)}
+	weather := newTestWeather(engine, Clear)
+	weather.Set(Storm)
+	assert.Equal(t, Clear, weather.Current)
+	assert.Equal(t, Storm, weather.Next)
+
+	// Partway through, the rain and the light are a blend of the two, and the dominant one is the kind
+	weather.Transition = 0.25
+	weather.apply()
+	assert.Equal(t, Clear, weather.Kind())
+	assert.InDelta(t, 1, weather.Rain.Density, 0.001)
+	assert.False(t, weather.Rain.IsHeavy())
+	assert.Equal(t, uint8(180), weather.Light(rl.NewColor(200, 200, 200, 255)).R)
+
+	weather.Transition = 0.75
+	weather.apply()
+	assert.Equal(t, Storm, weather.Kind())
+	assert.InDelta(t, 3, weather.Rain.Density, 0.001)
+	assert.True(t, weather.Rain.IsHeavy())
+	assert.True(t, weather.Shelter())
+
+	// Transitions finish after about 5 seconds, leaving the new weather as the current one
+	for i := 0; i < TickRate*5; i++ {
+		weather.Update()
+	}
+	assert.Equal(t, 1.0, weather.Transition)
+	assert.Equal(t, Storm, weather.Current)
+	assert.Equal(t, float64(WeatherPresets[Storm].Density), weather.Rain.Density)
+}
+
+func TestSeekShelter(t *testing.T) {
+	defer func(width int) { WorldWidth = width }(WorldWidth)
+	WorldWidth = 1000
+	engine := &Engine{RNG: NewRNG(1)}
+	weather := newTestWeather(engine, Clear)
+	house := PlaceBuilding(engine, GetHouse(engine, nil), nil, 400)
+	person := &Person{Effects: []func(*Person){SeekShelter, Wander}, Engine: engine}
+	person.Sprite.LevelX, person.Sprite.LevelY, person.Sprite.Speed = 100, float32(GroundLevel), 2
+
+	// Nobody minds a clear day
+	SeekShelter(person)
+	assert.False(t, person.Sheltered)
+	assert.False(t, person.OnTask)
+
+	weather.Current, weather.Next = Storm, Storm
+	for i := 0; i < 1000 && !person.Sheltered; i++ {
+		for _, effect := range person.Effects {
+			effect(person)
+		}
+	}
+	hitbox := house.GetHitbox()
+	assert.True(t, person.Sheltered)
+	assert.InDelta(t, hitbox.X+hitbox.Width/2, person.Sprite.LevelX, float64(person.Sprite.Speed))
+
+	// They stay put while it storms, then head back out once it passes
+	x := person.Sprite.LevelX
+	for i := 0; i < TickRate*10; i++ {
+		for _, effect := range person.Effects {
+			effect(person)
+		}
+	}
+	assert.True(t, person.Sheltered)
+	assert.Equal(t, x, person.Sprite.LevelX)
+
+	weather.Current, weather.Next = Clear, Clear
+	SeekShelter(person)
+	assert.False(t, person.Sheltered)
+}